@@ -0,0 +1,281 @@
+package pinhole
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io"
+	"os"
+)
+
+// APNG represents an animated PNG. It mirrors the layout of gif.GIF so that
+// the frames produced by Image can be collected in the same way.
+type APNG struct {
+	Image []*image.RGBA // The successive frames.
+	Delay []int         // The successive delay times, one per frame, in 100ths of a second, up to 65535.
+	// LoopCount controls the number of times an animation will be
+	// restarted during display.
+	// A LoopCount of 0 means to loop forever.
+	// A LoopCount of -1 means to show each frame only once.
+	// Otherwise, the animation is looped LoopCount+1 times.
+	LoopCount int
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+type apngEncoder struct {
+	w   io.Writer
+	seq uint32
+	err error
+}
+
+func (e *apngEncoder) writeChunk(name string, data []byte) {
+	if e.err != nil {
+		return
+	}
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[:4], uint32(len(data)))
+	copy(hdr[4:], name)
+	crc := crc32.NewIEEE()
+	crc.Write(hdr[4:])
+	crc.Write(data)
+	var foot [4]byte
+	binary.BigEndian.PutUint32(foot[:], crc.Sum32())
+	if _, e.err = e.w.Write(hdr[:]); e.err != nil {
+		return
+	}
+	if _, e.err = e.w.Write(data); e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(foot[:])
+}
+
+// EncodeAPNG writes the frames in a to w as an animated PNG. Each frame
+// after the first only stores the rectangle that changed since the previous
+// frame.
+func EncodeAPNG(w io.Writer, a *APNG) error {
+	if len(a.Image) == 0 {
+		return errors.New("apng: no frames")
+	}
+	bounds := a.Image[0].Bounds()
+	for _, img := range a.Image[1:] {
+		if img.Bounds().Size() != bounds.Size() {
+			return errors.New("apng: frames must all be the same size")
+		}
+	}
+	if len(a.Delay) != 0 && len(a.Delay) != len(a.Image) {
+		return errors.New("apng: mismatched image and delay lengths")
+	}
+	e := &apngEncoder{w: w}
+	if _, err := w.Write(pngHeader); err != nil {
+		return err
+	}
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(bounds.Dy()))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // truecolor with alpha
+	e.writeChunk("IHDR", ihdr)
+
+	var plays uint32
+	switch {
+	case a.LoopCount < 0:
+		plays = 1
+	case a.LoopCount > 0:
+		plays = uint32(a.LoopCount) + 1
+	}
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(a.Image)))
+	binary.BigEndian.PutUint32(actl[4:], plays)
+	e.writeChunk("acTL", actl)
+
+	var prev *image.RGBA
+	for i, img := range a.Image {
+		rect := bounds.Sub(bounds.Min)
+		if prev != nil {
+			rect = changedRect(prev, img)
+		}
+		var delay int
+		if len(a.Delay) != 0 {
+			delay = a.Delay[i]
+		}
+		// the delay is stored in 16 bits
+		if delay < 0 {
+			delay = 0
+		} else if delay > 0xffff {
+			delay = 0xffff
+		}
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], e.seq)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(rect.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(rect.Dy()))
+		binary.BigEndian.PutUint32(fctl[12:], uint32(rect.Min.X))
+		binary.BigEndian.PutUint32(fctl[16:], uint32(rect.Min.Y))
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:], 100)
+		fctl[24] = 0 // APNG_DISPOSE_OP_NONE
+		fctl[25] = 0 // APNG_BLEND_OP_SOURCE
+		e.writeChunk("fcTL", fctl)
+		e.seq++
+
+		data, err := compressRGBA(img, rect.Add(img.Bounds().Min))
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			e.writeChunk("IDAT", data)
+		} else {
+			fdat := make([]byte, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, e.seq)
+			copy(fdat[4:], data)
+			e.writeChunk("fdAT", fdat)
+			e.seq++
+		}
+		prev = img
+	}
+	e.writeChunk("IEND", nil)
+	return e.err
+}
+
+// SaveAPNG writes the frames in a to an animated PNG file at path.
+func SaveAPNG(path string, a *APNG) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := EncodeAPNG(file, a); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// changedRect returns the smallest rectangle, relative to the image origin,
+// that holds every pixel that differs between a and b. When nothing changed
+// a single pixel is returned because APNG frames cannot be empty.
+func changedRect(a, b *image.RGBA) image.Rectangle {
+	w, h := b.Bounds().Dx(), b.Bounds().Dy()
+	minx, miny, maxx, maxy := w, h, -1, -1
+	for y := 0; y < h; y++ {
+		ra := a.Pix[y*a.Stride : y*a.Stride+w*4]
+		rb := b.Pix[y*b.Stride : y*b.Stride+w*4]
+		if bytes.Equal(ra, rb) {
+			continue
+		}
+		if y < miny {
+			miny = y
+		}
+		maxy = y
+		for x := 0; x < w; x++ {
+			if !bytes.Equal(ra[x*4:x*4+4], rb[x*4:x*4+4]) {
+				if x < minx {
+					minx = x
+				}
+				break
+			}
+		}
+		for x := w - 1; x >= 0; x-- {
+			if !bytes.Equal(ra[x*4:x*4+4], rb[x*4:x*4+4]) {
+				if x > maxx {
+					maxx = x
+				}
+				break
+			}
+		}
+	}
+	if maxy < 0 {
+		return image.Rect(0, 0, 1, 1)
+	}
+	return image.Rect(minx, miny, maxx+1, maxy+1)
+}
+
+// compressRGBA returns the zlib compressed, filtered scanlines for the rect
+// portion of img. Pixels are converted from premultiplied to straight alpha.
+func compressRGBA(img *image.RGBA, rect image.Rectangle) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	n := rect.Dx() * 4
+	prev := make([]byte, n)
+	cur := make([]byte, n)
+	var filtered [5][]byte
+	for i := range filtered {
+		filtered[i] = make([]byte, n+1)
+		filtered[i][0] = byte(i)
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		i := img.PixOffset(rect.Min.X, y)
+		copy(cur, img.Pix[i:i+n])
+		for x := 0; x < n; x += 4 {
+			a := uint32(cur[x+3]) * 0x101
+			if a != 0 && a != 0xffff {
+				// the same rounding as color.NRGBAModel
+				cur[x+0] = uint8((uint32(cur[x+0]) * 0x101 * 0xffff / a) >> 8)
+				cur[x+1] = uint8((uint32(cur[x+1]) * 0x101 * 0xffff / a) >> 8)
+				cur[x+2] = uint8((uint32(cur[x+2]) * 0x101 * 0xffff / a) >> 8)
+			}
+		}
+		if _, err := zw.Write(filterRow(filtered, cur, prev)); err != nil {
+			return nil, err
+		}
+		prev, cur = cur, prev
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// filterRow applies each of the five PNG filters to cur and returns the one
+// with the smallest sum of absolute differences.
+func filterRow(out [5][]byte, cur, prev []byte) []byte {
+	const bpp = 4
+	for i := 0; i < len(cur); i++ {
+		var left, upleft byte
+		if i >= bpp {
+			left, upleft = cur[i-bpp], prev[i-bpp]
+		}
+		up := prev[i]
+		out[0][i+1] = cur[i]
+		out[1][i+1] = cur[i] - left
+		out[2][i+1] = cur[i] - up
+		out[3][i+1] = cur[i] - byte((int(left)+int(up))/2)
+		out[4][i+1] = cur[i] - paeth(left, up, upleft)
+	}
+	best, bestSum := 0, -1
+	for f := range out {
+		var sum int
+		for _, b := range out[f][1:] {
+			sum += abs(int(int8(b)))
+		}
+		if bestSum < 0 || sum < bestSum {
+			best, bestSum = f, sum
+		}
+	}
+	return out[best]
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
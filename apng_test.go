@@ -0,0 +1,149 @@
+package pinhole
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+type pngChunk struct {
+	name string
+	data []byte
+}
+
+// readChunks returns the chunks of a png file.
+func readChunks(t *testing.T, data []byte) []pngChunk {
+	if !bytes.HasPrefix(data, pngHeader) {
+		t.Fatal("missing png header")
+	}
+	data = data[len(pngHeader):]
+	var chunks []pngChunk
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatal("short chunk")
+		}
+		n := int(binary.BigEndian.Uint32(data))
+		chunks = append(chunks, pngChunk{string(data[4:8]), data[8 : 8+n]})
+		data = data[12+n:]
+	}
+	return chunks
+}
+
+func TestAPNGSequence(t *testing.T) {
+	var a APNG
+	for i := 0; i < 3; i++ {
+		img := image.NewRGBA(image.Rect(0, 0, 8, 6))
+		img.Set(i, i+1, color.RGBA{0xff, 0, 0, 0xff})
+		a.Image = append(a.Image, img)
+		a.Delay = append(a.Delay, 10*(i+1))
+	}
+	var buf bytes.Buffer
+	if err := EncodeAPNG(&buf, &a); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var seqs []uint32
+	var rects []image.Rectangle
+	for _, c := range readChunks(t, buf.Bytes()) {
+		names = append(names, c.name)
+		switch c.name {
+		case "acTL":
+			if n := binary.BigEndian.Uint32(c.data); n != 3 {
+				t.Fatalf("expected 3 frames, got %d", n)
+			}
+		case "fcTL":
+			seqs = append(seqs, binary.BigEndian.Uint32(c.data))
+			x, y := int(binary.BigEndian.Uint32(c.data[12:])), int(binary.BigEndian.Uint32(c.data[16:]))
+			w, h := int(binary.BigEndian.Uint32(c.data[4:])), int(binary.BigEndian.Uint32(c.data[8:]))
+			rects = append(rects, image.Rect(x, y, x+w, y+h))
+		case "fdAT":
+			seqs = append(seqs, binary.BigEndian.Uint32(c.data))
+		}
+	}
+	expect := "IHDR acTL fcTL IDAT fcTL fdAT fcTL fdAT IEND"
+	if s := strings.Join(names, " "); s != expect {
+		t.Fatalf("expected chunks %s, got %s", expect, s)
+	}
+	for i, seq := range seqs {
+		if seq != uint32(i) {
+			t.Fatalf("expected sequence numbers 0 to %d, got %v", len(seqs)-1, seqs)
+		}
+	}
+	// the later frames only hold the pixels that changed
+	for i, r := range []image.Rectangle{image.Rect(0, 0, 8, 6), image.Rect(0, 1, 2, 3), image.Rect(1, 2, 3, 4)} {
+		if rects[i] != r {
+			t.Fatalf("frame %d: expected %v, got %v", i, r, rects[i])
+		}
+	}
+	// the first frame is a plain png
+	img, err := png.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := img.At(0, 1).RGBA(); r != 0xffff {
+		t.Fatal("expected a red pixel in the first frame")
+	}
+}
+
+func TestChangedRect(t *testing.T) {
+	a := image.NewRGBA(image.Rect(10, 10, 20, 15))
+	b := image.NewRGBA(image.Rect(10, 10, 20, 15))
+	if r := changedRect(a, b); r != image.Rect(0, 0, 1, 1) {
+		t.Fatalf("no change: expected a single pixel, got %v", r)
+	}
+	b.Set(12, 11, color.White)
+	b.Set(17, 13, color.White)
+	if r := changedRect(a, b); r != image.Rect(2, 1, 8, 4) {
+		t.Fatalf("expected (2,1)-(8,4), got %v", r)
+	}
+}
+
+func TestAPNGAlpha(t *testing.T) {
+	// every premultiplied value of red for every alpha
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for a := 0; a < 256; a++ {
+		for c := 0; c <= a; c++ {
+			img.SetRGBA(c, a, color.RGBA{uint8(c), uint8(c / 2), 0, uint8(a)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := EncodeAPNG(&buf, &APNG{Image: []*image.RGBA{img}}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			expect := color.NRGBAModel.Convert(img.At(x, y))
+			if got := color.NRGBAModel.Convert(m.At(x, y)); got != expect {
+				t.Fatalf("pixel %d,%d: expected %v, got %v", x, y, expect, got)
+			}
+		}
+	}
+}
+
+func TestAPNGDelay(t *testing.T) {
+	a := APNG{Delay: []int{-5, 70000}}
+	for i := 0; i < 2; i++ {
+		a.Image = append(a.Image, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	}
+	var buf bytes.Buffer
+	if err := EncodeAPNG(&buf, &a); err != nil {
+		t.Fatal(err)
+	}
+	var delays []uint16
+	for _, c := range readChunks(t, buf.Bytes()) {
+		if c.name == "fcTL" {
+			delays = append(delays, binary.BigEndian.Uint16(c.data[20:]))
+		}
+	}
+	if len(delays) != 2 || delays[0] != 0 || delays[1] != 0xffff {
+		t.Fatalf("expected delays [0 65535], got %v", delays)
+	}
+}
@@ -0,0 +1,132 @@
+package pinhole
+
+// ArrowHead is the kind of head drawn by DrawArrow.
+type ArrowHead int
+
+const (
+	// ConeHead is a wireframe cone around the end of the arrow.
+	ConeHead ArrowHead = iota
+	// FlatHead is a filled triangle that always faces the viewer.
+	FlatHead
+)
+
+type ArrowOptions struct {
+	Head ArrowHead
+	// ConeLength and ConeRadius are the size of cone heads. Zero means 0.06
+	// and 0.02.
+	ConeLength float64
+	ConeRadius float64
+	// FlatSize is the length of flat heads in line widths at the tip, so
+	// that they get smaller with depth like the lines. Zero means 6.
+	FlatSize float64
+	// Double draws a second head that points at the start.
+	Double bool
+	// Mid places the heads in the middle of the arrow instead of at the
+	// ends, such as for the edges of a directed graph.
+	Mid bool
+}
+
+var DefaultArrowOptions = &ArrowOptions{
+	Head:       ConeHead,
+	ConeLength: 0.06,
+	ConeRadius: 0.02,
+	FlatSize:   6,
+}
+
+// DrawArrow draws an arrow from x1, y1, z1 that points at x2, y2, z2. Cone
+// heads that are longer than the arrow are scaled down to fit. The arrow is
+// drawn in its own group.
+func (p *Pinhole) DrawArrow(x1, y1, z1, x2, y2, z2 float64, opts *ArrowOptions) {
+	if opts == nil {
+		opts = DefaultArrowOptions
+	}
+	start, end := [3]float64{x1, y1, z1}, [3]float64{x2, y2, z2}
+	length := vlen(vsub(end, start))
+	if length == 0 {
+		p.DrawLine(x1, y1, z1, x2, y2, z2)
+		return
+	}
+	dir := vscale(vsub(end, start), 1/length)
+	head := arrowHead{
+		kind:   opts.Head,
+		length: opts.ConeLength,
+		radius: opts.ConeRadius,
+		size:   opts.FlatSize,
+	}
+	if head.length <= 0 {
+		head.length = 0.06
+	}
+	if head.radius <= 0 {
+		head.radius = 0.02
+	}
+	if head.size <= 0 {
+		head.size = 6
+	}
+	fit := length
+	if opts.Double || opts.Mid {
+		fit = length / 2
+	}
+	if head.length > fit {
+		head.radius *= fit / head.length
+		head.length = fit
+	}
+	p.Begin()
+	defer p.End()
+	tip1, tip2 := end, start
+	if opts.Mid {
+		tip1 = vscale(vadd(start, end), 0.5)
+		tip2 = tip1
+	}
+	// the shaft ends at the base of cone heads
+	s1, s2 := start, end
+	if opts.Head == ConeHead && !opts.Mid {
+		s2 = vsub(end, vscale(dir, head.length))
+		if opts.Double {
+			s1 = vadd(start, vscale(dir, head.length))
+		}
+	}
+	if s1 != s2 {
+		p.DrawLine(s1[0], s1[1], s1[2], s2[0], s2[1], s2[2])
+	}
+	p.drawHead(tip1, dir, length, head)
+	if opts.Double {
+		p.drawHead(tip2, vscale(dir, -1), length, head)
+	}
+}
+
+// arrowHead is the head of an arrow after the defaults and fitting.
+type arrowHead struct {
+	kind   ArrowHead
+	length float64 // cone length
+	radius float64 // cone radius
+	size   float64 // flat size
+}
+
+// drawHead draws a head with its tip at tip that points in the direction of
+// dir, for an arrow of the length.
+func (p *Pinhole) drawHead(tip, dir [3]float64, length float64, head arrowHead) {
+	if head.kind != FlatHead {
+		p.drawConeHead(tip, dir, head.length, head.radius)
+		return
+	}
+	// the head is drawn by Image, which only needs the direction of the
+	// arrow on the screen
+	from := vsub(tip, vscale(dir, length/100))
+	p.DrawLine(from[0], from[1], from[2], tip[0], tip[1], tip[2])
+	l := p.lines[len(p.lines)-1]
+	l.head = true
+	l.nocaps = true
+	l.scale = head.size
+}
+
+// drawConeHead draws a cone with its apex at tip that points in the
+// direction of dir.
+func (p *Pinhole) drawConeHead(tip, dir [3]float64, length, radius float64) {
+	const spokes = 8
+	_, u, v := (&ShapeOptions{Axis: dir}).basis()
+	ring := ringPoints(vsub(tip, vscale(dir, length)), u, v, radius, ringSteps(spokes))
+	p.drawRing(ring)
+	for i := 0; i < len(ring); i += len(ring) / spokes {
+		p.DrawLine(ring[i][0], ring[i][1], ring[i][2], tip[0], tip[1], tip[2])
+	}
+}
@@ -0,0 +1,57 @@
+package pinhole
+
+import (
+	"math"
+	"testing"
+)
+
+func TestArrowConeFits(t *testing.T) {
+	for _, opts := range []*ArrowOptions{
+		{ConeLength: 1, ConeRadius: 0.5},
+		{ConeLength: 1, ConeRadius: 0.5, Double: true},
+		{ConeLength: 1, ConeRadius: 0.5, Mid: true},
+	} {
+		p := New()
+		p.DrawArrow(0, 0, 0, 0.2, 0, 0, opts)
+		fit := 0.2
+		if opts.Double || opts.Mid {
+			fit = 0.1
+		}
+		for _, l := range p.lines {
+			for _, x := range []float64{l.x1, l.x2} {
+				if x < -1e-9 || x > 0.2+1e-9 {
+					t.Fatalf("%+v: line outside of the arrow at x=%v", opts, x)
+				}
+			}
+			// the cone radius is scaled with its length
+			for _, r := range []float64{math.Hypot(l.y1, l.z1), math.Hypot(l.y2, l.z2)} {
+				if r > fit*0.5+1e-9 {
+					t.Fatalf("%+v: cone radius %v, expected at most %v", opts, r, fit*0.5)
+				}
+			}
+		}
+	}
+}
+
+func TestArrowZeroOptions(t *testing.T) {
+	for _, head := range []ArrowHead{ConeHead, FlatHead} {
+		a, b := New(), New()
+		a.DrawArrow(0, 0, 0, 0.5, 0.2, 0, &ArrowOptions{Head: head})
+		b.DrawArrow(0, 0, 0, 0.5, 0.2, 0, &ArrowOptions{
+			Head:       head,
+			ConeLength: DefaultArrowOptions.ConeLength,
+			ConeRadius: DefaultArrowOptions.ConeRadius,
+			FlatSize:   DefaultArrowOptions.FlatSize,
+		})
+		if len(a.lines) != len(b.lines) {
+			t.Fatalf("head %d: expected %d lines, got %d", head, len(b.lines), len(a.lines))
+		}
+		for i := range a.lines {
+			la, lb := a.lines[i], b.lines[i]
+			if la.x1 != lb.x1 || la.y1 != lb.y1 || la.z1 != lb.z1 ||
+				la.x2 != lb.x2 || la.y2 != lb.y2 || la.z2 != lb.z2 || la.scale != lb.scale {
+				t.Fatalf("head %d: line %d differs from the defaults", head, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package pinhole
+
+import (
+	"math"
+	"strconv"
+)
+
+// Plane is a coordinate plane.
+type Plane int
+
+const (
+	PlaneXY Plane = iota
+	PlaneXZ
+	PlaneYZ
+)
+
+// point returns the point at u, v in the plane.
+func (plane Plane) point(u, v float64) [3]float64 {
+	switch plane {
+	case PlaneXZ:
+		return [3]float64{u, 0, v}
+	case PlaneYZ:
+		return [3]float64{0, u, v}
+	}
+	return [3]float64{u, v, 0}
+}
+
+// DrawGrid draws a square grid in the plane with lines every step from min
+// to max along both axes of the plane. The lines are split where they cross
+// so that they are depth sorted with the rest of the scene. The grid is
+// drawn in its own group.
+func (p *Pinhole) DrawGrid(plane Plane, min, max, step float64) {
+	if step <= 0 || max < min {
+		return
+	}
+	n := int(math.Floor((max-min)/step + 1e-9))
+	at := func(i int) float64 {
+		return min + float64(i)*step
+	}
+	p.Begin()
+	defer p.End()
+	for i := 0; i <= n; i++ {
+		for j := 0; j < n; j++ {
+			a, b := plane.point(at(i), at(j)), plane.point(at(i), at(j+1))
+			p.DrawLine(a[0], a[1], a[2], b[0], b[1], b[2])
+			a, b = plane.point(at(j), at(i)), plane.point(at(j+1), at(i))
+			p.DrawLine(a[0], a[1], a[2], b[0], b[1], b[2])
+		}
+	}
+}
+
+type AxesOptions struct {
+	// Min and Max are the ends of the X, Y and Z axes. The axes cross at
+	// the origin, or at the closest point to it within the ranges.
+	Min, Max [3]float64
+	// Ticks is about the number of ticks on each axis. The ticks are placed
+	// at round numbers. Zero draws no ticks.
+	Ticks int
+	// TickSize is the length of the tick marks and ArrowSize the length of
+	// the arrowheads.
+	TickSize  float64
+	ArrowSize float64
+	// Titles are drawn past the ends of the axes.
+	Titles [3]string
+	// Format formats the tick labels. The default uses as many decimals as
+	// the tick step needs. Tick labels are not drawn when it returns "".
+	Format func(v float64) string
+}
+
+var DefaultAxesOptions = &AxesOptions{
+	Min:       [3]float64{0, 0, 0},
+	Max:       [3]float64{0.8, 0.8, 0.8},
+	Ticks:     5,
+	TickSize:  0.02,
+	ArrowSize: 0.05,
+	Titles:    [3]string{"x", "y", "z"},
+}
+
+// DrawAxes draws the X, Y and Z axes with arrowheads, tick marks, tick
+// labels and titles. The axes are drawn in their own group.
+func (p *Pinhole) DrawAxes(opts *AxesOptions) {
+	if opts == nil {
+		opts = DefaultAxesOptions
+	}
+	var origin [3]float64
+	for i := range origin {
+		origin[i] = math.Max(opts.Min[i], math.Min(opts.Max[i], 0))
+	}
+	// the direction of the tick marks of each axis
+	tickDirs := [3][3]float64{{0, -1, 0}, {-1, 0, 0}, {-1, 0, 0}}
+	p.Begin()
+	defer p.End()
+	for axis := 0; axis < 3; axis++ {
+		if opts.Max[axis] <= opts.Min[axis] {
+			continue
+		}
+		var dir [3]float64
+		dir[axis] = 1
+		start, end := origin, origin
+		start[axis], end[axis] = opts.Min[axis], opts.Max[axis]
+		tip := vadd(end, vscale(dir, opts.ArrowSize))
+		p.DrawLine(start[0], start[1], start[2], tip[0], tip[1], tip[2])
+		p.drawArrowhead(tip, dir, tickDirs[axis], opts.ArrowSize)
+		if opts.Titles[axis] != "" {
+			at := vadd(tip, vscale(dir, opts.ArrowSize))
+			p.DrawString(at[0], at[1], at[2], opts.Titles[axis])
+		}
+		if opts.Ticks <= 0 {
+			continue
+		}
+		step := niceStep(opts.Max[axis]-opts.Min[axis], opts.Ticks)
+		format := opts.Format
+		if format == nil {
+			decimals := int(math.Max(0, -math.Floor(math.Log10(step)+1e-9)))
+			format = func(v float64) string {
+				return strconv.FormatFloat(v, 'f', decimals, 64)
+			}
+		}
+		first := math.Ceil(opts.Min[axis]/step - 1e-9)
+		for i := first; i*step <= opts.Max[axis]+step*1e-9; i++ {
+			v := i * step
+			if v == 0 {
+				v = 0 // not -0
+			}
+			at := origin
+			at[axis] = v
+			mark := vadd(at, vscale(tickDirs[axis], opts.TickSize))
+			p.DrawLine(at[0], at[1], at[2], mark[0], mark[1], mark[2])
+			if axis > 0 && v == origin[axis] {
+				// the crossing is labeled on the X axis
+				continue
+			}
+			if label := format(v); label != "" {
+				pos := vadd(at, vscale(tickDirs[axis], opts.TickSize*4))
+				p.DrawString(pos[0], pos[1], pos[2], label)
+			}
+		}
+	}
+}
+
+// drawArrowhead draws four lines from the tip back along dir, spread in the
+// side direction and the direction perpendicular to both.
+func (p *Pinhole) drawArrowhead(tip, dir, side [3]float64, size float64) {
+	back := vsub(tip, vscale(dir, size))
+	other := vcross(dir, side)
+	for _, s := range [][3]float64{side, vscale(side, -1), other, vscale(other, -1)} {
+		pt := vadd(back, vscale(s, size/3))
+		p.DrawLine(tip[0], tip[1], tip[2], pt[0], pt[1], pt[2])
+	}
+}
+
+// niceStep returns a step of 1, 2 or 5 times a power of ten that divides
+// the span into about n parts.
+func niceStep(span float64, n int) float64 {
+	raw := span / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	switch f := raw / mag; {
+	case f < 1.5:
+		return mag
+	case f < 3.5:
+		return 2 * mag
+	case f < 7.5:
+		return 5 * mag
+	}
+	return 10 * mag
+}
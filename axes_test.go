@@ -0,0 +1,55 @@
+package pinhole
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNiceStep(t *testing.T) {
+	for _, tc := range []struct {
+		span float64
+		n    int
+		step float64
+	}{
+		{1, 5, 0.2},
+		{1, 10, 0.1},
+		{0.8, 5, 0.2},
+		{10, 4, 2},
+		{100, 3, 20},
+		{8, 1, 10},
+		{0.003, 5, 0.0005},
+	} {
+		if step := niceStep(tc.span, tc.n); math.Abs(step-tc.step) > tc.step*1e-9 {
+			t.Fatalf("niceStep(%v, %d): expected %v, got %v", tc.span, tc.n, tc.step, step)
+		}
+	}
+}
+
+func TestAxesTicks(t *testing.T) {
+	opts := *DefaultAxesOptions
+	opts.Min = [3]float64{-1, 0.2, 0}
+	opts.Max = [3]float64{1, 1, 0}
+	opts.Ticks = 4
+	opts.Titles = [3]string{}
+	p := New()
+	p.DrawAxes(&opts)
+	var ticks [2][]float64
+	var labels []string
+	for _, l := range p.lines {
+		switch {
+		case l.str != "":
+			labels = append(labels, l.str)
+		case l.x1 == l.x2 && l.y1 == 0.2 && l.y2 == 0.2-opts.TickSize:
+			ticks[0] = append(ticks[0], math.Round(l.x1*1e9)/1e9)
+		case l.y1 == l.y2 && l.x1 == 0 && l.x2 == -opts.TickSize:
+			ticks[1] = append(ticks[1], math.Round(l.y1*1e9)/1e9)
+		}
+	}
+	// the axes cross at the point in the ranges closest to the origin, which
+	// is only labeled on the X axis, and the Z axis is empty
+	expect := "[[-1 -0.5 0 0.5 1] [0.2 0.4 0.6 0.8 1]] [-1.0 -0.5 0.0 0.5 1.0 0.4 0.6 0.8 1.0]"
+	if s := fmt.Sprint(ticks, labels); s != expect {
+		t.Fatalf("expected %s, got %s", expect, s)
+	}
+}
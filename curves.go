@@ -0,0 +1,209 @@
+package pinhole
+
+import "math"
+
+type CircleOptions struct {
+	// Normal is the direction the circle faces. The zero value is the Z
+	// axis, which puts the circle in the XY plane like DrawCircle.
+	Normal [3]float64
+	// Segments is the number of segments of a full circle. Arcs get their
+	// share of the segments. The default is 45.
+	Segments int
+}
+
+var DefaultCircleOptions = &CircleOptions{
+	Normal:   [3]float64{0, 0, 1},
+	Segments: circleSteps,
+}
+
+// plane returns two perpendicular unit vectors in the plane of the circle.
+// Angles start at the X axis, or at the Y axis when the normal is close to
+// the X axis, and go counterclockwise when seen from the normal.
+func (opts *CircleOptions) plane() (u, v [3]float64) {
+	n := vnorm(opts.Normal)
+	if n == ([3]float64{}) {
+		n = [3]float64{0, 0, 1}
+	}
+	ref := [3]float64{1, 0, 0}
+	if math.Abs(n[0]) > 0.9 {
+		ref = [3]float64{0, 1, 0}
+	}
+	u = vnorm(vsub(ref, vscale(n, vdot(ref, n))))
+	v = vcross(n, u)
+	return u, v
+}
+
+func (opts *CircleOptions) segments() int {
+	if opts.Segments < 3 {
+		return circleSteps
+	}
+	return opts.Segments
+}
+
+// DrawCircleWithOptions is like DrawCircle but the plane and the number of
+// segments of the circle are set by opts.
+func (p *Pinhole) DrawCircleWithOptions(x, y, z, radius float64, opts *CircleOptions) {
+	p.DrawEllipse(x, y, z, radius, radius, opts)
+}
+
+// DrawEllipse draws an ellipse with the radii rx and ry along the first and
+// second axes of the plane, which are X and Y by default.
+func (p *Pinhole) DrawEllipse(x, y, z, rx, ry float64, opts *CircleOptions) {
+	if opts == nil {
+		opts = DefaultCircleOptions
+	}
+	u, v := opts.plane()
+	points := make([][3]float64, opts.segments())
+	for i := range points {
+		a := math.Pi * 2 / float64(len(points)) * float64(i)
+		points[i] = vadd([3]float64{x, y, z}, vadd(vscale(u, math.Cos(a)*rx), vscale(v, math.Sin(a)*ry)))
+	}
+	p.drawRing(points)
+}
+
+// DrawArc draws the part of a circle from the start angle to the end angle,
+// in radians. The arc goes counterclockwise when end is greater than start.
+// An arc of a full turn or more is drawn as a circle.
+func (p *Pinhole) DrawArc(x, y, z, radius, start, end float64, opts *CircleOptions) {
+	if opts == nil {
+		opts = DefaultCircleOptions
+	}
+	if math.Abs(end-start) >= math.Pi*2 {
+		p.DrawCircleWithOptions(x, y, z, radius, opts)
+		return
+	}
+	u, v := opts.plane()
+	center := [3]float64{x, y, z}
+	n := int(math.Ceil(float64(opts.segments()) * math.Abs(end-start) / (math.Pi * 2)))
+	if n < 1 {
+		n = 1
+	}
+	points := make([][3]float64, n+1)
+	for i := range points {
+		a := start + (end-start)*float64(i)/float64(n)
+		points[i] = vadd(center, vadd(vscale(u, math.Cos(a)*radius), vscale(v, math.Sin(a)*radius)))
+	}
+	p.drawChain(points, false)
+}
+
+// SplineKind is the kind of curve drawn by DrawSpline.
+type SplineKind int
+
+const (
+	// CatmullRom is a curve that passes through all of the points.
+	CatmullRom SplineKind = iota
+	// BSpline is a uniform cubic B-spline that starts at the first point
+	// and ends at the last point, and is pulled towards the others.
+	BSpline
+)
+
+// curveTolerance is the largest distance between a curve and the segments
+// that are drawn for it, as a fraction of the extent of its control points.
+const curveTolerance = 0.001
+
+// curveExtent returns the length of the diagonal of the bounding box of the
+// points.
+func curveExtent(points [][3]float64) float64 {
+	lo, hi := points[0], points[0]
+	for _, pt := range points[1:] {
+		for i := range pt {
+			lo[i] = math.Min(lo[i], pt[i])
+			hi[i] = math.Max(hi[i], pt[i])
+		}
+	}
+	return vlen(vsub(hi, lo))
+}
+
+// tessellate appends the points of the curve f from t0 to t1, without the
+// point at t0, splitting the curve in halves until every half is within tol
+// of its chord.
+func tessellate(f func(t float64) [3]float64, t0, t1 float64, p0, p1 [3]float64, tol float64, depth int, points [][3]float64) [][3]float64 {
+	const minDepth, maxDepth = 2, 12
+	tm := (t0 + t1) / 2
+	pm := f(tm)
+	if depth >= maxDepth || (depth >= minDepth && segmentDistance(pm, p0, p1) <= tol) {
+		return append(points, p1)
+	}
+	points = tessellate(f, t0, tm, p0, pm, tol, depth+1, points)
+	return tessellate(f, tm, t1, pm, p1, tol, depth+1, points)
+}
+
+// segmentDistance returns the distance from pt to the segment from a to b.
+func segmentDistance(pt, a, b [3]float64) float64 {
+	ab := vsub(b, a)
+	var t float64
+	if d := vdot(ab, ab); d > 0 {
+		t = math.Max(0, math.Min(1, vdot(vsub(pt, a), ab)/d))
+	}
+	return vlen(vsub(pt, vadd(a, vscale(ab, t))))
+}
+
+// DrawBezier draws a Bezier curve with the control points. Three points make
+// a quadratic curve and four a cubic curve. The curve starts at the first
+// point and ends at the last.
+func (p *Pinhole) DrawBezier(points [][3]float64) {
+	if len(points) < 2 {
+		return
+	}
+	work := make([][3]float64, len(points))
+	bezier := func(t float64) [3]float64 {
+		// de Casteljau's algorithm
+		copy(work, points)
+		for n := len(work) - 1; n > 0; n-- {
+			for i := 0; i < n; i++ {
+				work[i] = vadd(work[i], vscale(vsub(work[i+1], work[i]), t))
+			}
+		}
+		return work[0]
+	}
+	first, last := points[0], points[len(points)-1]
+	tol := curveTolerance * curveExtent(points)
+	p.drawChain(tessellate(bezier, 0, 1, first, last, tol, 0, [][3]float64{first}), false)
+}
+
+// DrawSpline draws a smooth curve along the points.
+func (p *Pinhole) DrawSpline(kind SplineKind, points [][3]float64) {
+	if len(points) < 2 {
+		return
+	}
+	// repeat the end points so that every segment has four control points
+	// and the curve reaches the ends
+	var ctrl [][3]float64
+	first, last := points[0], points[len(points)-1]
+	switch kind {
+	case CatmullRom:
+		ctrl = append(append([][3]float64{first}, points...), last)
+	case BSpline:
+		ctrl = append(append([][3]float64{first, first}, points...), last, last)
+	default:
+		return
+	}
+	tol := curveTolerance * curveExtent(points)
+	curve := [][3]float64{first}
+	for i := 0; i+3 < len(ctrl); i++ {
+		p0, p1, p2, p3 := ctrl[i], ctrl[i+1], ctrl[i+2], ctrl[i+3]
+		var f func(t float64) [3]float64
+		if kind == CatmullRom {
+			a := vscale(p1, 2)
+			b := vsub(p2, p0)
+			c := vadd(vsub(vscale(p0, 2), vscale(p1, 5)), vsub(vscale(p2, 4), p3))
+			d := vadd(vsub(vscale(p1, 3), p0), vsub(p3, vscale(p2, 3)))
+			f = func(t float64) [3]float64 {
+				v := vadd(vadd(a, vscale(b, t)), vadd(vscale(c, t*t), vscale(d, t*t*t)))
+				return vscale(v, 0.5)
+			}
+		} else {
+			f = func(t float64) [3]float64 {
+				s := 1 - t
+				b0 := s * s * s / 6
+				b1 := (3*t*t*t - 6*t*t + 4) / 6
+				b2 := (-3*t*t*t + 3*t*t + 3*t + 1) / 6
+				b3 := t * t * t / 6
+				return vadd(vadd(vscale(p0, b0), vscale(p1, b1)), vadd(vscale(p2, b2), vscale(p3, b3)))
+			}
+		}
+		curve = tessellate(f, 0, 1, f(0), f(1), tol, 0, curve)
+	}
+	curve[len(curve)-1] = last
+	p.drawChain(curve, false)
+}
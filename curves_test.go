@@ -0,0 +1,187 @@
+package pinhole
+
+import (
+	"math"
+	"testing"
+)
+
+// chainPoints returns the points of the first chain of lines in p.
+func chainPoints(p *Pinhole) [][3]float64 {
+	var points [][3]float64
+	for l := p.lines[0].cfirst; l != nil; l = l.cnext {
+		if len(points) == 0 {
+			points = append(points, [3]float64{l.x1, l.y1, l.z1})
+		}
+		points = append(points, [3]float64{l.x2, l.y2, l.z2})
+	}
+	return points
+}
+
+func near(a, b [3]float64) bool {
+	return vlen(vsub(a, b)) < 1e-9
+}
+
+func TestArc(t *testing.T) {
+	for _, tc := range []struct {
+		start, end  float64
+		normal      [3]float64
+		first, last [3]float64
+	}{
+		{0, math.Pi / 2, [3]float64{0, 0, 1}, [3]float64{1, 0, 0}, [3]float64{0, 1, 0}},
+		{math.Pi, math.Pi / 2, [3]float64{0, 0, 1}, [3]float64{-1, 0, 0}, [3]float64{0, 1, 0}},
+		{0, math.Pi, [3]float64{0, 1, 0}, [3]float64{1, 0, 0}, [3]float64{-1, 0, 0}},
+		{0, math.Pi / 2, [3]float64{1, 0, 0}, [3]float64{0, 1, 0}, [3]float64{0, 0, 1}},
+	} {
+		p := New()
+		p.DrawArc(1, 2, 3, 2, tc.start, tc.end, &CircleOptions{Normal: tc.normal, Segments: 40})
+		points := chainPoints(p)
+		center := [3]float64{1, 2, 3}
+		first := vadd(center, vscale(tc.first, 2))
+		last := vadd(center, vscale(tc.last, 2))
+		if !near(points[0], first) || !near(points[len(points)-1], last) {
+			t.Fatalf("%v to %v: expected %v to %v, got %v to %v",
+				tc.start, tc.end, first, last, points[0], points[len(points)-1])
+		}
+		// a share of the 40 segments
+		if n := len(points) - 1; n != int(40*math.Abs(tc.end-tc.start)/(math.Pi*2)) {
+			t.Fatalf("%v to %v: got %d segments", tc.start, tc.end, n)
+		}
+		for _, pt := range points {
+			if math.Abs(vlen(vsub(pt, center))-2) > 1e-9 || math.Abs(vdot(vsub(pt, center), tc.normal)) > 1e-9 {
+				t.Fatalf("%v to %v: %v is not on the circle", tc.start, tc.end, pt)
+			}
+		}
+		if !p.lines[0].copen {
+			t.Fatal("expected an open chain")
+		}
+	}
+	// a full turn is a circle
+	p := New()
+	p.DrawArc(0, 0, 0, 1, 0, math.Pi*2, nil)
+	if p.lines[0].copen || len(p.lines) != circleSteps {
+		t.Fatal("expected a closed circle")
+	}
+}
+
+func TestEllipse(t *testing.T) {
+	p := New()
+	p.DrawEllipse(0, 0, 0, 2, 1, &CircleOptions{Normal: [3]float64{0, 0, 1}, Segments: 16})
+	if len(p.lines) != 16 {
+		t.Fatalf("expected 16 segments, got %d", len(p.lines))
+	}
+	for _, pt := range chainPoints(p) {
+		if math.Abs(pt[0]*pt[0]/4+pt[1]*pt[1]-1) > 1e-9 || pt[2] != 0 {
+			t.Fatalf("%v is not on the ellipse", pt)
+		}
+	}
+}
+
+// maxDeviation returns the largest distance from the curve f, sampled
+// finely, to the polyline.
+func maxDeviation(f func(t float64) [3]float64, points [][3]float64) float64 {
+	var dev float64
+	for i := 0; i <= 2000; i++ {
+		pt := f(float64(i) / 2000)
+		d := math.Inf(1)
+		for j := 0; j+1 < len(points); j++ {
+			d = math.Min(d, segmentDistance(pt, points[j], points[j+1]))
+		}
+		dev = math.Max(dev, d)
+	}
+	return dev
+}
+
+func TestBezier(t *testing.T) {
+	ctrl := [][3]float64{{-0.5, 0, 0}, {-0.2, 0.6, 0.1}, {0.3, -0.6, 0}, {0.5, 0, 0.2}}
+	p := New()
+	p.DrawBezier(ctrl)
+	points := chainPoints(p)
+	if !near(points[0], ctrl[0]) || !near(points[len(points)-1], ctrl[3]) {
+		t.Fatalf("expected the curve from %v to %v, got %v to %v", ctrl[0], ctrl[3], points[0], points[len(points)-1])
+	}
+	cubic := func(t float64) [3]float64 {
+		s := 1 - t
+		return vadd(vadd(vscale(ctrl[0], s*s*s), vscale(ctrl[1], 3*s*s*t)),
+			vadd(vscale(ctrl[2], 3*s*t*t), vscale(ctrl[3], t*t*t)))
+	}
+	if dev := maxDeviation(cubic, points); dev > curveTolerance*curveExtent(ctrl) {
+		t.Fatalf("the segments are %v from the curve", dev)
+	}
+}
+
+func TestCurveScale(t *testing.T) {
+	ctrl := [][3]float64{{-0.5, 0, 0}, {-0.2, 0.6, 0.1}, {0.3, -0.6, 0}, {0.5, 0, 0.2}}
+	var counts []int
+	// powers of two scale the control points exactly
+	for _, scale := range []float64{1.0 / (1 << 20), 1, 1 << 20} {
+		scaled := make([][3]float64, len(ctrl))
+		for i, c := range ctrl {
+			scaled[i] = vscale(c, scale)
+		}
+		for _, draw := range []func(p *Pinhole){
+			func(p *Pinhole) { p.DrawBezier(scaled) },
+			func(p *Pinhole) { p.DrawSpline(CatmullRom, scaled) },
+			func(p *Pinhole) { p.DrawSpline(BSpline, scaled) },
+		} {
+			p := New()
+			draw(p)
+			counts = append(counts, len(p.lines))
+		}
+	}
+	// the curves are split the same way at every scale
+	for i := 3; i < len(counts); i++ {
+		if counts[i] != counts[i%3] {
+			t.Fatalf("expected the same number of segments at every scale, got %v", counts)
+		}
+	}
+}
+
+func TestSpline(t *testing.T) {
+	ctrl := [][3]float64{{-0.5, 0, 0}, {-0.2, 0.4, 0}, {0, -0.1, 0.3}, {0.3, 0.2, 0}, {0.5, 0, 0}}
+	for _, kind := range []SplineKind{CatmullRom, BSpline} {
+		p := New()
+		p.DrawSpline(kind, ctrl)
+		points := chainPoints(p)
+		if points[0] != ctrl[0] || points[len(points)-1] != ctrl[len(ctrl)-1] {
+			t.Fatalf("kind %d: expected the curve from %v to %v, got %v to %v",
+				kind, ctrl[0], ctrl[len(ctrl)-1], points[0], points[len(points)-1])
+		}
+		if kind != CatmullRom {
+			continue
+		}
+		// the curve passes through every control point
+		for _, c := range ctrl {
+			var found bool
+			for _, pt := range points {
+				found = found || near(pt, c)
+			}
+			if !found {
+				t.Fatalf("the curve does not pass through %v", c)
+			}
+		}
+	}
+}
+
+func TestCurveAlongView(t *testing.T) {
+	// the curve is a single point on the screen
+	ctrl := [][3]float64{{0, 0, -.5}, {0, 0, 0}, {0, 0, .5}}
+	for _, draw := range []func(p *Pinhole){
+		func(p *Pinhole) { p.DrawBezier(ctrl) },
+		func(p *Pinhole) { p.DrawSpline(CatmullRom, ctrl) },
+		func(p *Pinhole) { p.DrawSpline(BSpline, ctrl) },
+	} {
+		p := New()
+		draw(p)
+		points := chainPoints(p)
+		if points[0] != ctrl[0] || points[len(points)-1] != ctrl[2] {
+			t.Fatalf("expected the curve from %v to %v, got %v to %v",
+				ctrl[0], ctrl[2], points[0], points[len(points)-1])
+		}
+		for i, pt := range points {
+			if pt[0] != 0 || pt[1] != 0 || (i > 0 && pt[2] < points[i-1][2]) {
+				t.Fatalf("expected points going up the z axis, got %v", points)
+			}
+		}
+		p.Image(100, 100, nil)
+	}
+}
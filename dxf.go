@@ -0,0 +1,313 @@
+package pinhole
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DXFLayers selects how lines are assigned to DXF layers.
+type DXFLayers int
+
+const (
+	// LayerByGroup puts each line on a layer named by its innermost named
+	// group, or on layer "0".
+	LayerByGroup DXFLayers = iota
+	// LayerByColor puts each line on a layer named by its RRGGBB color.
+	LayerByColor
+)
+
+type DXFOptions struct {
+	// Projected writes the 2D drawing as seen by Image instead of the 3D
+	// geometry. The drawing units are pixels.
+	Projected bool
+	// Width and Height are the size of the projected drawing.
+	Width, Height float64
+	// LineWidth and Scale are used like the ImageOptions of the same name
+	// for sizing the text of a projected drawing.
+	LineWidth float64
+	Scale     float64
+	Layers    DXFLayers
+}
+
+var DefaultDXFOptions = &DXFOptions{
+	Width:     500,
+	Height:    500,
+	LineWidth: 1,
+	Scale:     1,
+	Layers:    LayerByGroup,
+}
+
+// WriteDXF writes the scene to w as an AutoCAD R12 DXF file. Lines are
+// written as LINE entities, circles as CIRCLE entities when they are still
+// round or as closed POLYLINE entities otherwise, arcs as open POLYLINE
+// entities, dots as POINT entities and strings as TEXT entities. Flat
+// arrowheads are written as SOLID entities in projected drawings only.
+// Colors are written as the closest of the standard AutoCAD colors.
+func (p *Pinhole) WriteDXF(w io.Writer, opts *DXFOptions) error {
+	if opts == nil {
+		opts = DefaultDXFOptions
+	}
+	d := &dxfWriter{bw: bufio.NewWriter(w), opts: opts}
+	d.focal = math.Min(opts.Width, opts.Height) / 2
+
+	// collect the layers ahead of the entities
+	var layers []string
+	seen := make(map[string]bool)
+	for _, l := range p.lines {
+		name := d.layer(l)
+		if !seen[name] {
+			seen[name] = true
+			layers = append(layers, name)
+		}
+	}
+	d.pair(0, "SECTION")
+	d.pair(2, "HEADER")
+	d.pair(9, "$ACADVER")
+	d.pair(1, "AC1009")
+	d.pair(0, "ENDSEC")
+	d.pair(0, "SECTION")
+	d.pair(2, "TABLES")
+	d.pair(0, "TABLE")
+	d.pair(2, "LAYER")
+	d.pair(70, strconv.Itoa(len(layers)))
+	for _, name := range layers {
+		d.pair(0, "LAYER")
+		d.pair(2, name)
+		d.pair(70, "0")
+		d.pair(62, "7")
+		d.pair(6, "CONTINUOUS")
+	}
+	d.pair(0, "ENDTAB")
+	d.pair(0, "ENDSEC")
+
+	d.pair(0, "SECTION")
+	d.pair(2, "ENTITIES")
+	circles := make(map[*line]bool)
+	for _, l := range p.lines {
+		switch {
+		case l.str != "":
+			d.text(l)
+		case l.head:
+			if opts.Projected {
+				d.head(l)
+			}
+		case l.circle:
+			if !circles[l.cfirst] {
+				circles[l.cfirst] = true
+				d.circle(l.cfirst)
+			}
+		case l.x1 == l.x2 && l.y1 == l.y2 && l.z1 == l.z2:
+			d.entity("POINT", l)
+			d.point(10, l.x1, l.y1, l.z1)
+		default:
+			d.entity("LINE", l)
+			d.point(10, l.x1, l.y1, l.z1)
+			d.point(11, l.x2, l.y2, l.z2)
+		}
+	}
+	d.pair(0, "ENDSEC")
+	d.pair(0, "EOF")
+	return d.bw.Flush()
+}
+
+type dxfWriter struct {
+	bw    *bufio.Writer
+	opts  *DXFOptions
+	focal float64
+}
+
+func (d *dxfWriter) pair(code int, value string) {
+	d.bw.WriteString(strconv.Itoa(code))
+	d.bw.WriteByte('\n')
+	d.bw.WriteString(value)
+	d.bw.WriteByte('\n')
+}
+
+func (d *dxfWriter) float(code int, v float64) {
+	d.pair(code, strconv.FormatFloat(v, 'f', -1, 64))
+}
+
+// point writes the point with the x, y and z group codes starting at code,
+// projecting it first for a projected drawing.
+func (d *dxfWriter) point(code int, x, y, z float64) {
+	if d.opts.Projected {
+		x, y = d.project(x, y, z)
+		z = 0
+	}
+	d.float(code, x)
+	d.float(code+10, y)
+	d.float(code+20, z)
+}
+
+// project returns the position of the point in the drawing, with y pointing
+// up.
+func (d *dxfWriter) project(x, y, z float64) (float64, float64) {
+	px, py := projectPoint(x, y, z, d.opts.Width, d.opts.Height, d.focal, d.opts.Scale)
+	return px, d.opts.Height - py
+}
+
+func (d *dxfWriter) layer(l *line) string {
+	if d.opts.Layers == LayerByColor {
+		c := color.NRGBAModel.Convert(l.color).(color.NRGBA)
+		return fmt.Sprintf("%02X%02X%02X", c.R, c.G, c.B)
+	}
+	for g := l.group; g != nil; g = g.parent {
+		if g.name != "" {
+			return dxfLayerName(g.name)
+		}
+	}
+	return "0"
+}
+
+// dxfLayerName replaces the characters that are not allowed in layer names.
+func dxfLayerName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("<>/\\\":;?*|=`,", r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// entity starts an entity with the layer of the line and the standard color
+// that is closest to its color, because R12 has no true colors.
+func (d *dxfWriter) entity(kind string, l *line) {
+	d.pair(0, kind)
+	d.pair(8, d.layer(l))
+	c := color.NRGBAModel.Convert(l.color).(color.NRGBA)
+	d.pair(62, strconv.Itoa(aciColor(c)))
+}
+
+var aciColors = [...]color.NRGBA{
+	1: {255, 0, 0, 255},
+	2: {255, 255, 0, 255},
+	3: {0, 255, 0, 255},
+	4: {0, 255, 255, 255},
+	5: {0, 0, 255, 255},
+	6: {255, 0, 255, 255},
+	7: {0, 0, 0, 255}, // black or white, depending on the background
+	8: {128, 128, 128, 255},
+	9: {192, 192, 192, 255},
+}
+
+// aciColor returns the closest standard AutoCAD color index.
+func aciColor(c color.NRGBA) int {
+	best, bestDist := 7, -1
+	for i := 1; i < len(aciColors); i++ {
+		a := aciColors[i]
+		dr, dg, db := int(c.R)-int(a.R), int(c.G)-int(a.G), int(c.B)-int(a.B)
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+func (d *dxfWriter) text(l *line) {
+	var height float64
+	if d.opts.Projected {
+		height = 10 * lineWidthAtZ(l.z1, d.focal) * d.opts.LineWidth * l.scale
+	} else {
+		height = 10 * lineWidthAtZ(l.z1, 1) * l.scale
+	}
+	d.entity("TEXT", l)
+	d.point(10, l.x1, l.y1, l.z1)
+	d.float(40, height)
+	d.pair(1, l.str)
+	d.pair(72, "1") // centered
+	d.pair(73, "2") // middle
+	d.point(11, l.x1, l.y1, l.z1)
+}
+
+func (d *dxfWriter) head(l *line) {
+	x1, y1 := d.project(l.x1, l.y1, l.z1)
+	x2, y2 := d.project(l.x2, l.y2, l.z2)
+	t := lineWidthAtZ(l.z2, d.focal) * d.opts.LineWidth
+	tx, ty, lx, ly, rx, ry, ok := arrowhead(x1, y1, x2, y2, t, l.scale)
+	if !ok {
+		return
+	}
+	d.entity("SOLID", l)
+	for i, pt := range [][2]float64{{tx, ty}, {lx, ly}, {rx, ry}, {rx, ry}} {
+		d.float(10+i, pt[0])
+		d.float(20+i, pt[1])
+		d.float(30+i, 0)
+	}
+}
+
+// circle writes the circle or arc starting with the first segment. Circles
+// that are no longer round, from a projection or an uneven Scale, are written
+// as closed polylines and arcs as open polylines.
+func (d *dxfWriter) circle(first *line) {
+	var points [][3]float64
+	var last *line
+	for seg := first; seg != nil; seg = seg.cnext {
+		points = append(points, [3]float64{seg.x1, seg.y1, seg.z1})
+		last = seg
+	}
+	if first.copen {
+		points = append(points, [3]float64{last.x2, last.y2, last.z2})
+	} else if !d.opts.Projected && len(points) >= 3 {
+		var c [3]float64
+		for _, pt := range points {
+			c = vadd(c, pt)
+		}
+		c = vscale(c, 1/float64(len(points)))
+		radius := vlen(vsub(points[0], c))
+		round := radius > 0
+		for _, pt := range points {
+			if math.Abs(vlen(vsub(pt, c))-radius) > radius*1e-9 {
+				round = false
+				break
+			}
+		}
+		n := vnorm(vcross(vsub(points[0], c), vsub(points[1], c)))
+		if round && vlen(n) > 0 {
+			// convert the center to the object coordinate system using the
+			// arbitrary axis algorithm.
+			var ax [3]float64
+			if math.Abs(n[0]) < 1.0/64 && math.Abs(n[1]) < 1.0/64 {
+				ax = vnorm(vcross([3]float64{0, 1, 0}, n))
+			} else {
+				ax = vnorm(vcross([3]float64{0, 0, 1}, n))
+			}
+			ay := vnorm(vcross(n, ax))
+			d.entity("CIRCLE", first)
+			d.float(10, vdot(c, ax))
+			d.float(20, vdot(c, ay))
+			d.float(30, vdot(c, n))
+			d.float(40, radius)
+			d.float(210, n[0])
+			d.float(220, n[1])
+			d.float(230, n[2])
+			return
+		}
+	}
+	d.entity("POLYLINE", first)
+	d.pair(66, "1")
+	var flags int
+	if !first.copen {
+		flags |= 1 // closed
+	}
+	if !d.opts.Projected {
+		flags |= 8 // 3D polyline
+	}
+	d.pair(70, strconv.Itoa(flags))
+	d.float(10, 0)
+	d.float(20, 0)
+	d.float(30, 0)
+	for _, pt := range points {
+		d.entity("VERTEX", first)
+		d.point(10, pt[0], pt[1], pt[2])
+		if !d.opts.Projected {
+			d.pair(70, "32") // 3D polyline vertex
+		}
+	}
+	d.pair(0, "SEQEND")
+	d.pair(8, d.layer(first))
+}
@@ -0,0 +1,32 @@
+package pinhole
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestDXFColors(t *testing.T) {
+	p := New()
+	p.DrawLine(0, 0, 0, 1, 0, 0)
+	p.DrawLine(0, 0, 0, 0, 1, 0)
+	p.Colorize(color.RGBA{0xf0, 0x10, 0x10, 0xff})
+	var buf bytes.Buffer
+	if err := p.WriteDXF(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	lines := strings.Split(strings.ReplaceAll(buf.String(), "\r\n", "\n"), "\n")
+	for i := 0; i+1 < len(lines); i += 2 {
+		switch strings.TrimSpace(lines[i]) {
+		case "62":
+			codes = append(codes, strings.TrimSpace(lines[i+1]))
+		case "420":
+			t.Fatal("R12 files cannot have true colors")
+		}
+	}
+	if s := strings.Join(codes, ","); !strings.HasSuffix(s, "1,1") {
+		t.Fatalf("expected the lines in red, got colors %s", s)
+	}
+}
@@ -0,0 +1,108 @@
+package pinhole
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Format is an image file format.
+type Format int
+
+const (
+	FormatPNG Format = iota
+	FormatJPEG
+	FormatGIF
+)
+
+type EncodeOptions struct {
+	// ImageOptions are used to render the image.
+	ImageOptions *ImageOptions
+	// PNGCompression is the compression level of PNG images.
+	PNGCompression png.CompressionLevel
+	// JPEGQuality is the quality of JPEG images, from 1 to 100.
+	JPEGQuality int
+	// GIFColors is the maximum number of colors in GIF images, up to 256.
+	GIFColors int
+}
+
+var DefaultEncodeOptions = &EncodeOptions{
+	ImageOptions:   DefaultImageOptions,
+	PNGCompression: png.DefaultCompression,
+	JPEGQuality:    jpeg.DefaultQuality,
+	GIFColors:      256,
+}
+
+// Encode renders the scene and writes the image to w in the format.
+func (p *Pinhole) Encode(w io.Writer, format Format, width, height int, opts *EncodeOptions) error {
+	if opts == nil {
+		opts = DefaultEncodeOptions
+	}
+	img := p.Image(width, height, opts.ImageOptions)
+	switch format {
+	case FormatPNG:
+		enc := png.Encoder{CompressionLevel: opts.PNGCompression}
+		return enc.Encode(w, img)
+	case FormatJPEG:
+		quality := opts.JPEGQuality
+		if quality <= 0 {
+			quality = jpeg.DefaultQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatGIF:
+		return gif.Encode(w, paletted(img, opts.GIFColors), nil)
+	}
+	return errors.New("unknown format")
+}
+
+// paletted converts the image to at most ncolors colors using median cut.
+// Pixels that are mostly transparent get a transparent palette entry.
+func paletted(img *image.RGBA, ncolors int) *image.Paletted {
+	if ncolors <= 0 || ncolors > 256 {
+		ncolors = 256
+	}
+	bounds := img.Bounds()
+	hist := make(map[uint32]int)
+	var transparent bool
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.RGBAAt(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				transparent = true
+				continue
+			}
+			hist[1<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B)]++
+		}
+	}
+	if transparent && ncolors > 1 {
+		ncolors--
+	}
+	colors := medianCut(hist, ncolors)
+	pal := make(color.Palette, 0, len(colors)+1)
+	for _, rgb := range colors {
+		pal = append(pal, color.RGBA{uint8(rgb >> 16), uint8(rgb >> 8), uint8(rgb), 0xff})
+	}
+	if transparent || len(pal) == 0 {
+		pal = append(pal, color.RGBA{})
+	}
+	index := make(map[uint32]uint8, len(hist))
+	for rgb := range hist {
+		index[rgb] = uint8(nearestColor(colors, rgb))
+	}
+	out := image.NewPaletted(bounds, pal)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.RGBAAt(x, y)).(color.NRGBA)
+			if c.A < 0x80 {
+				out.SetColorIndex(x, y, uint8(len(pal)-1))
+				continue
+			}
+			out.SetColorIndex(x, y, index[1<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B)])
+		}
+	}
+	return out
+}
@@ -0,0 +1,47 @@
+package pinhole
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	p := New()
+	p.DrawCube(-0.3, -0.3, -0.3, 0.3, 0.3, 0.3)
+	p.Colorize(color.RGBA{0xff, 0, 0, 0xff})
+	p.DrawCircle(0, 0, 0, 0.4)
+	for _, tc := range []struct {
+		name   string
+		format Format
+		decode func(r io.Reader) (image.Image, error)
+	}{
+		{"png", FormatPNG, png.Decode},
+		{"jpeg", FormatJPEG, jpeg.Decode},
+		{"gif", FormatGIF, gif.Decode},
+	} {
+		var buf bytes.Buffer
+		opts := &EncodeOptions{ImageOptions: DefaultImageOptions, JPEGQuality: 90, GIFColors: 16}
+		if err := p.Encode(&buf, tc.format, 60, 40, opts); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		img, err := tc.decode(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if img.Bounds() != image.Rect(0, 0, 60, 40) {
+			t.Fatalf("%s: expected 60x40, got %v", tc.name, img.Bounds())
+		}
+		if tc.format == FormatGIF && len(img.(*image.Paletted).Palette) > 16 {
+			t.Fatalf("gif: expected at most 16 colors, got %d", len(img.(*image.Paletted).Palette))
+		}
+	}
+	if err := p.Encode(&bytes.Buffer{}, Format(-1), 10, 10, nil); err == nil {
+		t.Fatal("expected an unknown format error")
+	}
+}
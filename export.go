@@ -0,0 +1,154 @@
+package pinhole
+
+import (
+	"bufio"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// polylines calls fn for each shape in the scene. Circles are passed as a
+// single closed polyline and arcs as a single open polyline, other lines as
+// two points. Strings and flat arrowheads are skipped.
+func (p *Pinhole) polylines(fn func(points [][3]float64, closed bool, l *line) error) error {
+	seen := make(map[*line]bool)
+	var points [][3]float64
+	for _, l := range p.lines {
+		if l.str != "" || l.head {
+			continue
+		}
+		if !l.circle {
+			points = append(points[:0], [3]float64{l.x1, l.y1, l.z1})
+			if l.x1 != l.x2 || l.y1 != l.y2 || l.z1 != l.z2 {
+				points = append(points, [3]float64{l.x2, l.y2, l.z2})
+			}
+			if err := fn(points, false, l); err != nil {
+				return err
+			}
+			continue
+		}
+		if seen[l.cfirst] {
+			continue
+		}
+		seen[l.cfirst] = true
+		points = points[:0]
+		var last *line
+		for seg := l.cfirst; seg != nil; seg = seg.cnext {
+			points = append(points, [3]float64{seg.x1, seg.y1, seg.z1})
+			last = seg
+		}
+		if l.copen {
+			points = append(points, [3]float64{last.x2, last.y2, last.z2})
+		}
+		if err := fn(points, !l.copen, l.cfirst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// WriteObj writes the scene to w as a Wavefront OBJ file. Lines and circles
+// are written as line elements, with circles joined back into a single
+// closed polyline, and dots as point elements. Strings, flat arrowheads and
+// colors are not written, and dots lose their radius.
+func (p *Pinhole) WriteObj(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	verts := make(map[[3]float64]int)
+	var idxs []int
+	var cgroup *group
+	err := p.polylines(func(points [][3]float64, closed bool, l *line) error {
+		if l.group != cgroup {
+			cgroup = l.group
+			if cgroup != nil && cgroup.name != "" {
+				bw.WriteString("g " + cgroup.name + "\n")
+			} else {
+				bw.WriteString("g\n")
+			}
+		}
+		idxs = idxs[:0]
+		for _, pt := range points {
+			idx, ok := verts[pt]
+			if !ok {
+				idx = len(verts) + 1
+				verts[pt] = idx
+				bw.WriteString("v " + formatFloat(pt[0]) + " " +
+					formatFloat(pt[1]) + " " + formatFloat(pt[2]) + "\n")
+			}
+			idxs = append(idxs, idx)
+		}
+		if closed {
+			idxs = append(idxs, idxs[0])
+		}
+		if len(idxs) == 1 {
+			bw.WriteString("p")
+		} else {
+			bw.WriteString("l")
+		}
+		for _, idx := range idxs {
+			bw.WriteString(" " + strconv.Itoa(idx))
+		}
+		_, err := bw.WriteString("\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WritePLY writes the scene to w as an ASCII PLY file with vertex and edge
+// elements. The colors of the lines are written as the edge colors. Dots are
+// written as vertices without edges, so they lose their color and radius, and
+// a dot at the end of a line is lost. Strings and flat arrowheads are not
+// written.
+func (p *Pinhole) WritePLY(w io.Writer) error {
+	verts := make(map[[3]float64]int)
+	var vlist [][3]float64
+	type plyEdge struct {
+		v1, v2 int
+		color  color.NRGBA
+	}
+	var edges []plyEdge
+	vertex := func(pt [3]float64) int {
+		idx, ok := verts[pt]
+		if !ok {
+			idx = len(vlist)
+			verts[pt] = idx
+			vlist = append(vlist, pt)
+		}
+		return idx
+	}
+	p.polylines(func(points [][3]float64, closed bool, l *line) error {
+		c := color.NRGBAModel.Convert(l.color).(color.NRGBA)
+		for i, pt := range points {
+			v1 := vertex(pt)
+			if i+1 < len(points) {
+				edges = append(edges, plyEdge{v1, vertex(points[i+1]), c})
+			} else if closed {
+				edges = append(edges, plyEdge{v1, vertex(points[0]), c})
+			}
+		}
+		return nil
+	})
+	bw := bufio.NewWriter(w)
+	bw.WriteString("ply\nformat ascii 1.0\ncomment pinhole\n")
+	bw.WriteString("element vertex " + strconv.Itoa(len(vlist)) + "\n")
+	bw.WriteString("property double x\nproperty double y\nproperty double z\n")
+	bw.WriteString("element edge " + strconv.Itoa(len(edges)) + "\n")
+	bw.WriteString("property int vertex1\nproperty int vertex2\n")
+	bw.WriteString("property uchar red\nproperty uchar green\nproperty uchar blue\nproperty uchar alpha\n")
+	bw.WriteString("end_header\n")
+	for _, v := range vlist {
+		bw.WriteString(formatFloat(v[0]) + " " + formatFloat(v[1]) + " " + formatFloat(v[2]) + "\n")
+	}
+	for _, e := range edges {
+		bw.WriteString(strconv.Itoa(e.v1) + " " + strconv.Itoa(e.v2) + " " +
+			strconv.Itoa(int(e.color.R)) + " " + strconv.Itoa(int(e.color.G)) + " " +
+			strconv.Itoa(int(e.color.B)) + " " + strconv.Itoa(int(e.color.A)) + "\n")
+	}
+	return bw.Flush()
+}
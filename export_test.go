@@ -0,0 +1,60 @@
+package pinhole
+
+import (
+	"bytes"
+	"testing"
+)
+
+// exportCounts returns the number of dots and other lines in the scene.
+func exportCounts(p *Pinhole) (dots, lines int) {
+	for _, l := range p.lines {
+		if l.x1 == l.x2 && l.y1 == l.y2 && l.z1 == l.z2 {
+			dots++
+		} else {
+			lines++
+		}
+	}
+	return dots, lines
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	p := New()
+	p.DrawCube(-0.5, -0.5, -0.5, 0.5, 0.5, 0.5)
+	p.DrawCircle(0, 0, 0, 0.3)
+	p.DrawDot(0, 0, 0, 0.05)
+	p.DrawDot(0.1, 0.2, 0.3, 0.05)
+	dots, lines := exportCounts(p)
+	for _, format := range []struct {
+		name  string
+		write func(p *Pinhole, buf *bytes.Buffer) error
+		load  func(p *Pinhole, buf *bytes.Buffer) error
+	}{
+		{"obj",
+			func(p *Pinhole, buf *bytes.Buffer) error { return p.WriteObj(buf) },
+			func(p *Pinhole, buf *bytes.Buffer) error { return p.LoadObj(buf) }},
+		{"ply",
+			func(p *Pinhole, buf *bytes.Buffer) error { return p.WritePLY(buf) },
+			func(p *Pinhole, buf *bytes.Buffer) error { return p.LoadPLY(buf) }},
+	} {
+		var buf bytes.Buffer
+		if err := format.write(p, &buf); err != nil {
+			t.Fatal(err)
+		}
+		q := New()
+		if err := format.load(q, &buf); err != nil {
+			t.Fatalf("%s: %v", format.name, err)
+		}
+		qdots, qlines := exportCounts(q)
+		if qdots != dots || qlines != lines {
+			t.Fatalf("%s: expected %d dots and %d lines, got %d and %d",
+				format.name, dots, lines, qdots, qlines)
+		}
+		for _, l := range q.lines {
+			if l.x1 == l.x2 && l.y1 == l.y2 && l.z1 == l.z2 &&
+				!(l.x1 == 0 && l.y1 == 0 && l.z1 == 0) &&
+				!(l.x1 == 0.1 && l.y1 == 0.2 && l.z1 == 0.3) {
+				t.Fatalf("%s: unexpected dot at %v %v %v", format.name, l.x1, l.y1, l.z1)
+			}
+		}
+	}
+}
@@ -0,0 +1,509 @@
+package pinhole
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"strings"
+)
+
+type GLTFOptions struct {
+	// Resolver opens the external buffers referenced by a .gltf file. When
+	// Resolver is nil only embedded buffers can be loaded.
+	Resolver func(uri string) (io.ReadCloser, error)
+	// DotRadius is the radius of the dots drawn for point primitives. Zero
+	// means 0.02.
+	DotRadius float64
+
+	MeshOptions
+}
+
+var DefaultGLTFOptions = &GLTFOptions{
+	DotRadius: 0.02,
+}
+
+func (p *Pinhole) LoadGLTF(r io.Reader) error {
+	return p.LoadGLTFWithOptions(r, nil)
+}
+
+// LoadGLTFWithOptions reads a glTF 2.0 .gltf or .glb file from r. The node
+// hierarchy of the default scene is walked, applying each node transform,
+// and every node is mapped to a named group, see BeginGroup. Triangle and
+// line primitives are drawn as lines, point primitives as dots, using the
+// material base color as the line color.
+func (p *Pinhole) LoadGLTFWithOptions(r io.Reader, opts *GLTFOptions) error {
+	if opts == nil {
+		opts = DefaultGLTFOptions
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var bin []byte
+	if bytes.HasPrefix(data, []byte("glTF")) {
+		data, bin, err = splitGLB(data)
+		if err != nil {
+			return err
+		}
+	}
+	var doc gltfDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("gltf: %s", err.Error())
+	}
+	l := &gltfLoader{p: p, doc: &doc, opts: opts}
+	l.radius = opts.DotRadius
+	if l.radius <= 0 {
+		l.radius = 0.02
+	}
+	for i, b := range doc.Buffers {
+		buf, err := loadGLTFBuffer(b.URI, bin, i, opts.Resolver)
+		if err != nil {
+			return fmt.Errorf("gltf: buffer %d: %s", i, err.Error())
+		}
+		if len(buf) < b.ByteLength {
+			return fmt.Errorf("gltf: buffer %d: too short", i)
+		}
+		l.buffers = append(l.buffers, buf)
+	}
+
+	var roots []int
+	if len(doc.Scenes) > 0 {
+		scene := 0
+		if doc.Scene != nil {
+			scene = *doc.Scene
+		}
+		if scene < 0 || scene >= len(doc.Scenes) {
+			return fmt.Errorf("gltf: invalid scene: %d", scene)
+		}
+		roots = doc.Scenes[scene].Nodes
+	} else {
+		// no scenes, use every node that is not a child of another node
+		child := make([]bool, len(doc.Nodes))
+		for _, n := range doc.Nodes {
+			for _, c := range n.Children {
+				if c >= 0 && c < len(child) {
+					child[c] = true
+				}
+			}
+		}
+		for i := range doc.Nodes {
+			if !child[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+	l.mb = newMeshBuilder(p, &opts.MeshOptions)
+	defer l.mb.finish()
+	l.visited = make([]bool, len(doc.Nodes))
+	for _, n := range roots {
+		if err := l.node(n, identityMatrix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type gltfDoc struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes  []gltfNode `json:"nodes"`
+	Meshes []struct {
+		Name       string          `json:"name"`
+		Primitives []gltfPrimitive `json:"primitives"`
+	} `json:"meshes"`
+	Materials []struct {
+		PBR *struct {
+			BaseColorFactor []float64 `json:"baseColorFactor"`
+		} `json:"pbrMetallicRoughness"`
+	} `json:"materials"`
+	Accessors   []gltfAccessor `json:"accessors"`
+	BufferViews []struct {
+		Buffer     int `json:"buffer"`
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+		ByteStride int `json:"byteStride"`
+	} `json:"bufferViews"`
+	Buffers []struct {
+		URI        string `json:"uri"`
+		ByteLength int    `json:"byteLength"`
+	} `json:"buffers"`
+}
+
+type gltfNode struct {
+	Name        string    `json:"name"`
+	Children    []int     `json:"children"`
+	Mesh        *int      `json:"mesh"`
+	Matrix      []float64 `json:"matrix"`
+	Translation []float64 `json:"translation"`
+	Rotation    []float64 `json:"rotation"`
+	Scale       []float64 `json:"scale"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Material   *int           `json:"material"`
+	Mode       *int           `json:"mode"`
+}
+
+type gltfAccessor struct {
+	BufferView    *int            `json:"bufferView"`
+	ByteOffset    int             `json:"byteOffset"`
+	ComponentType int             `json:"componentType"`
+	Normalized    bool            `json:"normalized"`
+	Count         int             `json:"count"`
+	Type          string          `json:"type"`
+	Sparse        json.RawMessage `json:"sparse"`
+}
+
+// splitGLB returns the JSON and binary chunks of a .glb file.
+func splitGLB(data []byte) (js, bin []byte, err error) {
+	if len(data) < 12 || binary.LittleEndian.Uint32(data[4:]) != 2 {
+		return nil, nil, errors.New("gltf: unsupported glb version")
+	}
+	data = data[12:]
+	for len(data) >= 8 {
+		n := int(binary.LittleEndian.Uint32(data))
+		typ := string(data[4:8])
+		if n < 0 || n > len(data)-8 {
+			return nil, nil, errors.New("gltf: invalid glb chunk")
+		}
+		switch typ {
+		case "JSON":
+			js = data[8 : 8+n]
+		case "BIN\x00":
+			bin = data[8 : 8+n]
+		}
+		data = data[8+n:]
+	}
+	if js == nil {
+		return nil, nil, errors.New("gltf: missing glb json chunk")
+	}
+	return js, bin, nil
+}
+
+func loadGLTFBuffer(uri string, bin []byte, index int,
+	resolve func(uri string) (io.ReadCloser, error),
+) ([]byte, error) {
+	if uri == "" {
+		if index != 0 || bin == nil {
+			return nil, errors.New("missing uri")
+		}
+		return bin, nil
+	}
+	if strings.HasPrefix(uri, "data:") {
+		i := strings.Index(uri, ";base64,")
+		if i == -1 {
+			return nil, errors.New("unsupported data uri")
+		}
+		return base64.StdEncoding.DecodeString(uri[i+8:])
+	}
+	if resolve == nil {
+		return nil, errors.New("external buffers require a resolver")
+	}
+	if u, err := url.PathUnescape(uri); err == nil {
+		uri = u
+	}
+	rc, err := resolve(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+type gltfLoader struct {
+	p       *Pinhole
+	doc     *gltfDoc
+	opts    *GLTFOptions
+	buffers [][]byte
+	mb      *meshBuilder
+	radius  float64
+	visited []bool
+}
+
+func (l *gltfLoader) node(index int, parent [16]float64) error {
+	if index < 0 || index >= len(l.doc.Nodes) {
+		return fmt.Errorf("gltf: invalid node: %d", index)
+	}
+	if l.visited[index] {
+		return fmt.Errorf("gltf: node %d: cycle in node hierarchy", index)
+	}
+	l.visited[index] = true
+	defer func() { l.visited[index] = false }()
+	n := &l.doc.Nodes[index]
+	m := mulMatrix(parent, n.localMatrix())
+	l.p.BeginGroup(n.Name)
+	defer l.p.End()
+	if n.Mesh != nil {
+		if *n.Mesh < 0 || *n.Mesh >= len(l.doc.Meshes) {
+			return fmt.Errorf("gltf: node %d: invalid mesh: %d", index, *n.Mesh)
+		}
+		for i, prim := range l.doc.Meshes[*n.Mesh].Primitives {
+			if err := l.primitive(&prim, m); err != nil {
+				return fmt.Errorf("gltf: mesh %d: primitive %d: %s", *n.Mesh, i, err.Error())
+			}
+		}
+	}
+	for _, c := range n.Children {
+		if err := l.node(c, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *gltfLoader) primitive(prim *gltfPrimitive, m [16]float64) error {
+	pos, ok := prim.Attributes["POSITION"]
+	if !ok {
+		return nil
+	}
+	values, size, err := l.accessor(pos)
+	if err != nil {
+		return err
+	}
+	if size != 3 {
+		return errors.New("position accessor is not a VEC3")
+	}
+	verts := make([][3]float64, len(values)/3)
+	for i := range verts {
+		verts[i] = transformPoint(m, values[i*3], values[i*3+1], values[i*3+2])
+	}
+	var indices []int
+	if prim.Indices != nil {
+		values, _, err := l.accessor(*prim.Indices)
+		if err != nil {
+			return err
+		}
+		indices = make([]int, len(values))
+		for i, v := range values {
+			if v < 0 || int(v) >= len(verts) {
+				return fmt.Errorf("invalid index: %v", v)
+			}
+			indices[i] = int(v)
+		}
+	} else {
+		indices = make([]int, len(verts))
+		for i := range indices {
+			indices[i] = i
+		}
+	}
+	var c color.Color = color.Black
+	if prim.Material != nil && *prim.Material >= 0 && *prim.Material < len(l.doc.Materials) {
+		if pbr := l.doc.Materials[*prim.Material].PBR; pbr != nil && len(pbr.BaseColorFactor) == 4 {
+			f := pbr.BaseColorFactor
+			c = color.NRGBA{unitByte(f[0]), unitByte(f[1]), unitByte(f[2]), unitByte(f[3])}
+		}
+	}
+	mode := 4
+	if prim.Mode != nil {
+		mode = *prim.Mode
+	}
+	pts := func(idx ...int) [][3]float64 {
+		points := make([][3]float64, len(idx))
+		for i, j := range idx {
+			points[i] = verts[j]
+		}
+		return points
+	}
+	switch mode {
+	case 0: // POINTS
+		for _, i := range indices {
+			v := verts[i]
+			l.p.DrawDot(v[0], v[1], v[2], l.radius)
+			l.p.lines[len(l.p.lines)-1].color = c
+		}
+	case 1: // LINES
+		for i := 0; i+1 < len(indices); i += 2 {
+			l.mb.polyline(pts(indices[i], indices[i+1]), false, c)
+		}
+	case 2: // LINE_LOOP
+		l.mb.polyline(pts(indices...), true, c)
+	case 3: // LINE_STRIP
+		l.mb.polyline(pts(indices...), false, c)
+	case 4: // TRIANGLES
+		for i := 0; i+2 < len(indices); i += 3 {
+			l.mb.polyline(pts(indices[i], indices[i+1], indices[i+2]), true, c)
+		}
+	case 5: // TRIANGLE_STRIP
+		for i := 0; i+2 < len(indices); i++ {
+			l.mb.polyline(pts(indices[i], indices[i+1], indices[i+2]), true, c)
+		}
+	case 6: // TRIANGLE_FAN
+		for i := 1; i+1 < len(indices); i++ {
+			l.mb.polyline(pts(indices[0], indices[i], indices[i+1]), true, c)
+		}
+	default:
+		return fmt.Errorf("invalid mode: %d", mode)
+	}
+	return nil
+}
+
+// gltfMaxZeroCount is the largest count of an accessor without a buffer
+// view, whose values are all zero.
+const gltfMaxZeroCount = 1 << 20
+
+// accessor returns the values of the accessor as a flat list along with the
+// number of components per element.
+func (l *gltfLoader) accessor(index int) ([]float64, int, error) {
+	if index < 0 || index >= len(l.doc.Accessors) {
+		return nil, 0, fmt.Errorf("invalid accessor: %d", index)
+	}
+	a := &l.doc.Accessors[index]
+	if len(a.Sparse) > 0 {
+		return nil, 0, fmt.Errorf("accessor %d: sparse accessors are not supported", index)
+	}
+	var size int
+	switch a.Type {
+	case "SCALAR":
+		size = 1
+	case "VEC2":
+		size = 2
+	case "VEC3":
+		size = 3
+	case "VEC4":
+		size = 4
+	default:
+		return nil, 0, fmt.Errorf("accessor %d: unsupported type: %s", index, a.Type)
+	}
+	var csize int
+	switch a.ComponentType {
+	case 5120, 5121: // BYTE, UNSIGNED_BYTE
+		csize = 1
+	case 5122, 5123: // SHORT, UNSIGNED_SHORT
+		csize = 2
+	case 5125, 5126: // UNSIGNED_INT, FLOAT
+		csize = 4
+	default:
+		return nil, 0, fmt.Errorf("accessor %d: invalid component type: %d", index, a.ComponentType)
+	}
+	if a.Count < 0 {
+		return nil, 0, fmt.Errorf("accessor %d: invalid count: %d", index, a.Count)
+	}
+	if a.BufferView == nil {
+		// the values are all zero and only limited by the count
+		if a.Count > gltfMaxZeroCount {
+			return nil, 0, fmt.Errorf("accessor %d: count too large: %d", index, a.Count)
+		}
+		return make([]float64, a.Count*size), size, nil
+	}
+	if *a.BufferView < 0 || *a.BufferView >= len(l.doc.BufferViews) {
+		return nil, 0, fmt.Errorf("accessor %d: invalid buffer view", index)
+	}
+	bv := l.doc.BufferViews[*a.BufferView]
+	if bv.Buffer < 0 || bv.Buffer >= len(l.buffers) {
+		return nil, 0, fmt.Errorf("accessor %d: invalid buffer", index)
+	}
+	buf := l.buffers[bv.Buffer]
+	if bv.ByteOffset < 0 || bv.ByteLength < 0 || bv.ByteOffset > len(buf) ||
+		bv.ByteLength > len(buf)-bv.ByteOffset {
+		return nil, 0, fmt.Errorf("accessor %d: buffer view out of range", index)
+	}
+	buf = buf[bv.ByteOffset : bv.ByteOffset+bv.ByteLength]
+	stride := bv.ByteStride
+	if stride == 0 {
+		stride = size * csize
+	} else if stride < size*csize {
+		return nil, 0, fmt.Errorf("accessor %d: invalid byte stride: %d", index, stride)
+	}
+	// check the range before allocating so that the count cannot be larger
+	// than the buffer allows
+	if a.Count > 0 && (a.ByteOffset < 0 || a.ByteOffset > len(buf)-size*csize ||
+		a.Count-1 > (len(buf)-a.ByteOffset-size*csize)/stride) {
+		return nil, 0, fmt.Errorf("accessor %d: out of range", index)
+	}
+	values := make([]float64, a.Count*size)
+	for i := 0; i < a.Count; i++ {
+		for j := 0; j < size; j++ {
+			b := buf[a.ByteOffset+i*stride+j*csize:]
+			var v float64
+			switch a.ComponentType {
+			case 5120:
+				v = float64(int8(b[0]))
+				if a.Normalized {
+					v = math.Max(v/127, -1)
+				}
+			case 5121:
+				v = float64(b[0])
+				if a.Normalized {
+					v /= 255
+				}
+			case 5122:
+				v = float64(int16(binary.LittleEndian.Uint16(b)))
+				if a.Normalized {
+					v = math.Max(v/32767, -1)
+				}
+			case 5123:
+				v = float64(binary.LittleEndian.Uint16(b))
+				if a.Normalized {
+					v /= 65535
+				}
+			case 5125:
+				v = float64(binary.LittleEndian.Uint32(b))
+			case 5126:
+				v = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+			}
+			values[i*size+j] = v
+		}
+	}
+	return values, size, nil
+}
+
+// identityMatrix is a column-major 4x4 identity matrix.
+var identityMatrix = [16]float64{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+// localMatrix returns the column-major transform of the node, either from
+// its matrix or from its translation, rotation and scale.
+func (n *gltfNode) localMatrix() [16]float64 {
+	if len(n.Matrix) == 16 {
+		var m [16]float64
+		copy(m[:], n.Matrix)
+		return m
+	}
+	t := [3]float64{0, 0, 0}
+	r := [4]float64{0, 0, 0, 1}
+	s := [3]float64{1, 1, 1}
+	copy(t[:], n.Translation)
+	copy(r[:], n.Rotation)
+	copy(s[:], n.Scale)
+	x, y, z, w := r[0], r[1], r[2], r[3]
+	return [16]float64{
+		(1 - 2*(y*y+z*z)) * s[0], 2 * (x*y + z*w) * s[0], 2 * (x*z - y*w) * s[0], 0,
+		2 * (x*y - z*w) * s[1], (1 - 2*(x*x+z*z)) * s[1], 2 * (y*z + x*w) * s[1], 0,
+		2 * (x*z + y*w) * s[2], 2 * (y*z - x*w) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+// mulMatrix returns a*b for column-major 4x4 matrices.
+func mulMatrix(a, b [16]float64) [16]float64 {
+	var m [16]float64
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var v float64
+			for k := 0; k < 4; k++ {
+				v += a[k*4+row] * b[col*4+k]
+			}
+			m[col*4+row] = v
+		}
+	}
+	return m
+}
+
+func transformPoint(m [16]float64, x, y, z float64) [3]float64 {
+	return [3]float64{
+		m[0]*x + m[4]*y + m[8]*z + m[12],
+		m[1]*x + m[5]*y + m[9]*z + m[13],
+		m[2]*x + m[6]*y + m[10]*z + m[14],
+	}
+}
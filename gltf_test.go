@@ -0,0 +1,139 @@
+package pinhole
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+// gltfFloats returns a buffer of float32 values.
+func gltfFloats(vals ...float64) []byte {
+	b := make([]byte, len(vals)*4)
+	for i, v := range vals {
+		binary.LittleEndian.PutUint32(b[i*4:], math.Float32bits(float32(v)))
+	}
+	return b
+}
+
+// gltfURI returns a data uri for the buffer.
+func gltfURI(b []byte) string {
+	return "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(b)
+}
+
+func TestGLTFAccessorErrors(t *testing.T) {
+	uri := gltfURI(gltfFloats(0, 0, 0, 1, 0, 0))
+	for _, tc := range []struct {
+		name     string
+		accessor string
+		err      string
+	}{
+		{"negative count", `{"bufferView":0,"componentType":5126,"count":-1,"type":"VEC3"}`, "invalid count"},
+		{"huge count", `{"bufferView":0,"componentType":5126,"count":4611686018427387904,"type":"VEC3"}`, "out of range"},
+		{"huge zero count", `{"componentType":5126,"count":4611686018427387904,"type":"VEC3"}`, "count too large"},
+		{"past end", `{"bufferView":0,"componentType":5126,"count":3,"type":"VEC3"}`, "out of range"},
+		{"offset", `{"bufferView":0,"byteOffset":16,"componentType":5126,"count":1,"type":"VEC3"}`, "out of range"},
+	} {
+		doc := `{"buffers":[{"uri":"` + uri + `","byteLength":24}],` +
+			`"bufferViews":[{"buffer":0,"byteLength":24}],` +
+			`"accessors":[` + tc.accessor + `],` +
+			`"meshes":[{"primitives":[{"attributes":{"POSITION":0},"mode":1}]}],` +
+			`"nodes":[{"mesh":0}]}`
+		err := New().LoadGLTF(strings.NewReader(doc))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected %q error, got %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+// gltfLine is a scene with a line from 0,0,0 to 1,0,0 in a node hierarchy.
+const gltfLine = `"bufferViews":[{"buffer":0,"byteLength":24}],
+"accessors":[{"bufferView":0,"componentType":5126,"count":2,"type":"VEC3"}],
+"meshes":[{"primitives":[{"attributes":{"POSITION":0},"mode":1}]}],
+"nodes":[
+	{"name":"root","translation":[0,0,1],"children":[1,2]},
+	{"name":"turned","mesh":0,"scale":[2,2,2],"rotation":[0,0,0.7071067811865476,0.7071067811865476]},
+	{"name":"moved","mesh":0,"matrix":[1,0,0,0, 0,1,0,0, 0,0,1,0, 0,3,0,1]}
+],
+"scenes":[{"nodes":[0]}]`
+
+func pointDist(a, b [3]float64) float64 {
+	return math.Sqrt((a[0]-b[0])*(a[0]-b[0]) + (a[1]-b[1])*(a[1]-b[1]) + (a[2]-b[2])*(a[2]-b[2]))
+}
+
+// glb returns a .glb file with the json and binary chunks.
+func glb(js string, bin []byte) []byte {
+	for len(js)%4 != 0 {
+		js += " "
+	}
+	var b bytes.Buffer
+	chunk := func(typ string, data []byte) {
+		binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+		b.WriteString(typ)
+		b.Write(data)
+	}
+	b.WriteString("glTF")
+	binary.Write(&b, binary.LittleEndian, uint32(2))
+	binary.Write(&b, binary.LittleEndian, uint32(12+8+len(js)+8+len(bin)))
+	chunk("JSON", []byte(js))
+	chunk("BIN\x00", bin)
+	return b.Bytes()
+}
+
+func TestGLTFNodes(t *testing.T) {
+	bin := gltfFloats(0, 0, 0, 1, 0, 0)
+	for name, data := range map[string][]byte{
+		"gltf": []byte(`{"buffers":[{"uri":"` + gltfURI(bin) + `","byteLength":24}],` + gltfLine + `}`),
+		"glb":  glb(`{"buffers":[{"byteLength":24}],`+gltfLine+`}`, bin),
+	} {
+		p := New()
+		if err := p.LoadGLTF(bytes.NewReader(data)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(p.lines) != 2 {
+			t.Fatalf("%s: expected 2 lines, got %d", name, len(p.lines))
+		}
+		names := lineNames(p)
+		for i, expect := range []struct {
+			from, to [3]float64
+			group    string
+		}{
+			{[3]float64{0, 0, 1}, [3]float64{0, 2, 1}, "root/turned"},
+			{[3]float64{0, 3, 1}, [3]float64{1, 3, 1}, "root/moved"},
+		} {
+			l := p.lines[i]
+			from, to := [3]float64{l.x1, l.y1, l.z1}, [3]float64{l.x2, l.y2, l.z2}
+			if pointDist(from, expect.from) > 1e-9 || pointDist(to, expect.to) > 1e-9 {
+				t.Fatalf("%s: line %d: expected %v-%v, got %v-%v", name, i, expect.from, expect.to, from, to)
+			}
+			if names[i] != expect.group {
+				t.Fatalf("%s: line %d: expected group %q, got %q", name, i, expect.group, names[i])
+			}
+		}
+	}
+}
+
+func TestGLBErrors(t *testing.T) {
+	good := glb(`{}`, nil)
+	version := append([]byte{}, good...)
+	version[4] = 1
+	chunk := append([]byte{}, good...)
+	chunk[12] = 0xff
+	nojson := append([]byte{}, good...)
+	copy(nojson[16:], "TEXT")
+	for name, tc := range map[string]struct {
+		data []byte
+		err  string
+	}{
+		"version": {version, "unsupported glb version"},
+		"chunk":   {chunk, "invalid glb chunk"},
+		"json":    {nojson, "missing glb json chunk"},
+	} {
+		err := New().LoadGLTF(bytes.NewReader(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected %q error, got %v", name, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package pinhole
+
+import (
+	"bufio"
+	"encoding/json"
+	"html"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+type HTMLOptions struct {
+	Title     string
+	Width     int
+	Height    int
+	BGColor   color.Color
+	LineWidth float64
+	Scale     float64
+}
+
+var DefaultHTMLOptions = &HTMLOptions{
+	Title:     "pinhole",
+	Width:     500,
+	Height:    500,
+	BGColor:   color.White,
+	LineWidth: 1,
+	Scale:     1,
+}
+
+// WriteHTML writes the scene to w as a single HTML page that needs no
+// network access. The page embeds the scene in the JSON scene format and
+// draws it on a canvas the way Image does. Dragging with the mouse orbits
+// the scene, the wheel zooms and a double click restores the view.
+func (p *Pinhole) WriteHTML(w io.Writer, opts *HTMLOptions) error {
+	if opts == nil {
+		opts = DefaultHTMLOptions
+	}
+	scene, err := p.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var bg string
+	if opts.BGColor != nil {
+		bg = cssColor(opts.BGColor)
+	}
+	settings, err := json.Marshal(struct {
+		Width      int     `json:"width"`
+		Height     int     `json:"height"`
+		Background string  `json:"background,omitempty"`
+		LineWidth  float64 `json:"lineWidth"`
+		Scale      float64 `json:"scale"`
+	}{opts.Width, opts.Height, bg, opts.LineWidth, opts.Scale})
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	bw.WriteString("<title>" + html.EscapeString(opts.Title) + "</title>\n")
+	bw.WriteString(htmlStyle)
+	bw.WriteString("</head>\n<body>\n<canvas id=\"pinhole\"></canvas>\n<script>\n")
+	// json.Marshal escapes '<', so the data cannot end the script element.
+	bw.WriteString("var opts = ")
+	bw.Write(settings)
+	bw.WriteString(";\nvar scene = ")
+	bw.Write(scene)
+	bw.WriteString(";\n")
+	bw.WriteString(htmlScript)
+	bw.WriteString("</script>\n</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// cssColor returns the color as a CSS rgba() value.
+func cssColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return "rgba(" + strconv.Itoa(int(n.R)) + "," + strconv.Itoa(int(n.G)) + "," +
+		strconv.Itoa(int(n.B)) + "," + strconv.FormatFloat(float64(n.A)/0xff, 'g', 4, 64) + ")"
+}
+
+const htmlStyle = `<style>
+html, body { margin: 0; height: 100%; }
+body { display: flex; align-items: center; justify-content: center; }
+canvas { cursor: grab; touch-action: none; }
+canvas:active { cursor: grabbing; }
+</style>
+`
+
+// htmlScript mirrors Image: the same projection, line widths, depth order,
+// round caps and circle joins.
+const htmlScript = `(function() {
+var canvas = document.getElementById("pinhole");
+var ctx = canvas.getContext("2d");
+var ratio = window.devicePixelRatio || 1;
+canvas.width = opts.width * ratio;
+canvas.height = opts.height * ratio;
+canvas.style.width = opts.width + "px";
+canvas.style.height = opts.height + "px";
+
+function parseColor(c) {
+	if (c === undefined) {
+		return "#000000";
+	}
+	if (typeof c === "string") {
+		return c;
+	}
+	var a = c[3];
+	if (a === 0) {
+		return "rgba(0,0,0,0)";
+	}
+	return "rgba(" + Math.round(c[0] * 255 / a) + "," +
+		Math.round(c[1] * 255 / a) + "," +
+		Math.round(c[2] * 255 / a) + "," + (a / 65535) + ")";
+}
+
+var lines = [];
+var circles = {};
+scene.lines.forEach(function(l) {
+	var line = {
+		a: l.from,
+		b: l.to || l.from,
+		color: parseColor(l.color),
+		text: l.text || "",
+		head: !!l.head,
+		scale: l.scale || (l.radius ? l.radius * 100 : 1),
+		circle: !!l.circle,
+		open: !!l.open,
+		nocaps: l.nocaps !== undefined ? l.nocaps : !!l.circle
+	};
+	if (line.circle) {
+		var ring = circles[l.circle] || (circles[l.circle] = []);
+		ring[l.segment || 0] = line;
+		line.ring = ring;
+	}
+	line.min = [0, 1, 2].map(function(i) { return Math.min(line.a[i], line.b[i]); });
+	line.max = [0, 1, 2].map(function(i) { return Math.max(line.a[i], line.b[i]); });
+	lines.push(line);
+});
+
+var yaw = 0, pitch = 0, zoom = 1;
+
+// byDistance: far lines first
+function byDistance(l1, l2) {
+	var a = l1.vmin, b = l1.vmax, c = l2.vmin, d = l2.vmax;
+	for (var i = 2; i >= 0; i--) {
+		if (b[i] !== d[i]) {
+			return (b[i] > d[i]) === (i === 2) ? -1 : 1;
+		}
+		if (a[i] !== c[i]) {
+			return (a[i] > c[i]) === (i === 2) ? -1 : 1;
+		}
+	}
+	return 0;
+}
+
+function render() {
+	var w = opts.width, h = opts.height, f = Math.min(w, h) / 2;
+	var scale = opts.scale * zoom;
+	var cy = Math.cos(yaw), sy = Math.sin(yaw);
+	var cp = Math.cos(pitch), sp = Math.sin(pitch);
+	function view(p) {
+		var x = p[2] * sy + p[0] * cy, z = p[2] * cy - p[0] * sy;
+		return [x, p[1] * cp - z * sp, p[1] * sp + z * cp];
+	}
+	function project(v) {
+		var x = v[0] * scale * f, y = v[1] * scale * f, z = v[2] * scale * f;
+		var zz = z + f || Number.MIN_VALUE;
+		return [x * (f / zz) + w / 2, -(y * (f / zz) - h / 2)];
+	}
+	function lineWidth(z, l) {
+		return ((z * -1 + 1) / 2) * f * 0.04 * opts.lineWidth * l.scale;
+	}
+	function onscreen(p1, p2) {
+		return Math.min(p1[0], p2[0]) <= w && Math.max(p1[0], p2[0]) >= 0 &&
+			Math.min(p1[1], p2[1]) <= h && Math.max(p1[1], p2[1]) >= 0;
+	}
+	function destination(p, angle, distance) {
+		return [p[0] + Math.cos(angle) * distance, p[1] + Math.sin(angle) * distance];
+	}
+	function corners(l) {
+		var a = Math.atan2(l.p1[1] - l.p2[1], l.p1[0] - l.p2[0]);
+		return [
+			destination(l.p1, a - Math.PI / 2, l.t1 / 2),
+			destination(l.p1, a + Math.PI / 2, l.t1 / 2),
+			destination(l.p2, a + Math.PI / 2, l.t2 / 2),
+			destination(l.p2, a - Math.PI / 2, l.t2 / 2)
+		];
+	}
+
+	lines.forEach(function(l) {
+		var va = view(l.a), vb = view(l.b);
+		l.vmin = [0, 1, 2].map(function(i) { return Math.min(va[i], vb[i]); });
+		l.vmax = [0, 1, 2].map(function(i) { return Math.max(va[i], vb[i]); });
+		l.p1 = project(va);
+		l.p2 = project(vb);
+		l.t1 = lineWidth(va[2], l);
+		l.t2 = lineWidth(vb[2], l);
+		l.vz2 = vb[2];
+		l.corners = null;
+	});
+	var order = lines.slice().sort(byDistance);
+
+	ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+	ctx.clearRect(0, 0, w, h);
+	if (opts.background) {
+		ctx.fillStyle = opts.background;
+		ctx.fillRect(0, 0, w, h);
+	}
+	var caps = {};
+	function insertCap(l, p) {
+		var key = l.color + "|" + p.join(",");
+		if (caps[key]) {
+			return false;
+		}
+		caps[key] = true;
+		return true;
+	}
+	var cubicCorner = 1.0 / 3 * 2;
+	order.forEach(function(l) {
+		ctx.fillStyle = l.color;
+		ctx.beginPath();
+		if (l.text) {
+			var sz = 10 * l.t1;
+			ctx.font = sz + "px sans-serif";
+			ctx.fillText(l.text, l.p1[0] - ctx.measureText(l.text).width / 2, l.p1[1] + sz * .4);
+			return;
+		}
+		if (l.head) {
+			if (l.p1[0] === l.p2[0] && l.p1[1] === l.p2[1]) {
+				return;
+			}
+			// a flat arrowhead sized by the line width at the tip
+			var t = lineWidth(l.vz2, {scale: 1});
+			var ha = Math.atan2(l.p1[1] - l.p2[1], l.p1[0] - l.p2[0]);
+			var tip = destination(l.p2, ha, -t / 2);
+			var base = destination(tip, ha, t * l.scale);
+			var left = destination(base, ha + Math.PI / 2, t * l.scale * 0.4);
+			var right = destination(base, ha - Math.PI / 2, t * l.scale * 0.4);
+			ctx.moveTo(tip[0], tip[1]);
+			ctx.lineTo(left[0], left[1]);
+			ctx.lineTo(right[0], right[1]);
+			ctx.closePath();
+			ctx.fill();
+			return;
+		}
+		if (l.circle) {
+			if (!l.corners) {
+				// join the midpoints of neighboring segments
+				var coords = l.ring.map(function(seg) {
+					return (seg.corners = corners(seg));
+				});
+				for (var i = l.open ? 1 : 0; i < coords.length; i++) {
+					var c1 = coords[(i + coords.length - 1) % coords.length], c2 = coords[i];
+					var m1 = [(c2[0][0] + c1[3][0]) / 2, (c2[0][1] + c1[3][1]) / 2];
+					var m2 = [(c2[1][0] + c1[2][0]) / 2, (c2[1][1] + c1[2][1]) / 2];
+					c2[0] = c1[3] = m1;
+					c2[1] = c1[2] = m2;
+				}
+			}
+			ctx.moveTo(l.corners[0][0], l.corners[0][1]);
+			for (var j = 1; j < 4; j++) {
+				ctx.lineTo(l.corners[j][0], l.corners[j][1]);
+			}
+			ctx.closePath();
+			ctx.fill();
+			if (l.open) {
+				// round caps for the ends of the chain
+				var ends = [];
+				if (l === l.ring[0]) {
+					ends.push([l.a, l.p1, l.t1]);
+				}
+				if (l === l.ring[l.ring.length - 1]) {
+					ends.push([l.b, l.p2, l.t2]);
+				}
+				ends.forEach(function(e) {
+					if (insertCap(l, e[0]) && e[2] >= 2) {
+						ctx.beginPath();
+						ctx.arc(e[1][0], e[1][1], e[2] / 2, 0, Math.PI * 2);
+						ctx.fill();
+					}
+				});
+			}
+			return;
+		}
+		if (!onscreen(l.p1, l.p2)) {
+			return;
+		}
+		if (l.p1[0] === l.p2[0] && l.p1[1] === l.p2[1]) {
+			ctx.arc(l.p1[0], l.p1[1], Math.max(l.t1 / 2, 0), 0, Math.PI * 2);
+			ctx.fill();
+			return;
+		}
+		var cap1 = false, cap2 = false;
+		if (!l.nocaps) {
+			cap1 = insertCap(l, l.a) && l.t1 >= 2;
+			cap2 = insertCap(l, l.b) && l.t2 >= 2;
+		}
+		var d = corners(l);
+		var a = Math.atan2(l.p1[1] - l.p2[1], l.p1[0] - l.p2[0]);
+		ctx.moveTo(d[0][0], d[0][1]);
+		if (cap1) {
+			var a1 = destination(d[0], a, l.t1 * cubicCorner);
+			var a2 = destination(d[1], a, l.t1 * cubicCorner);
+			ctx.bezierCurveTo(a1[0], a1[1], a2[0], a2[1], d[1][0], d[1][1]);
+		} else {
+			ctx.lineTo(d[1][0], d[1][1]);
+		}
+		ctx.lineTo(d[2][0], d[2][1]);
+		if (cap2) {
+			var a3 = destination(d[2], a, -l.t2 * cubicCorner);
+			var a4 = destination(d[3], a, -l.t2 * cubicCorner);
+			ctx.bezierCurveTo(a3[0], a3[1], a4[0], a4[1], d[3][0], d[3][1]);
+		} else {
+			ctx.lineTo(d[3][0], d[3][1]);
+		}
+		ctx.closePath();
+		ctx.fill();
+	});
+}
+
+var pending = false;
+function redraw() {
+	if (!pending) {
+		pending = true;
+		window.requestAnimationFrame(function() {
+			pending = false;
+			render();
+		});
+	}
+}
+
+var drag = null;
+canvas.addEventListener("pointerdown", function(e) {
+	drag = {x: e.clientX, y: e.clientY};
+	canvas.setPointerCapture(e.pointerId);
+});
+canvas.addEventListener("pointermove", function(e) {
+	if (!drag) {
+		return;
+	}
+	yaw -= (e.clientX - drag.x) * 0.01;
+	pitch += (e.clientY - drag.y) * 0.01;
+	pitch = Math.max(-Math.PI / 2, Math.min(Math.PI / 2, pitch));
+	drag = {x: e.clientX, y: e.clientY};
+	redraw();
+});
+canvas.addEventListener("pointerup", function() {
+	drag = null;
+});
+canvas.addEventListener("wheel", function(e) {
+	e.preventDefault();
+	zoom *= Math.exp(-e.deltaY * 0.001);
+	redraw();
+}, {passive: false});
+canvas.addEventListener("dblclick", function() {
+	yaw = 0;
+	pitch = 0;
+	zoom = 1;
+	redraw();
+});
+render();
+})();
+`
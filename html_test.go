@@ -0,0 +1,57 @@
+package pinhole
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	p := New()
+	p.BeginGroup("box")
+	p.DrawCube(-0.3, -0.3, -0.3, 0.3, 0.3, 0.3)
+	p.End()
+	p.DrawCircle(0, 0, 0, 0.4)
+	p.Colorize(color.RGBA{0, 0, 0xff, 0xff})
+	p.DrawString(0, 0.5, 0, "</script><b>")
+	var buf bytes.Buffer
+	if err := p.WriteHTML(&buf, &HTMLOptions{Title: "a<b", Width: 300, Height: 200, LineWidth: 1, Scale: 1}); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.Contains(s, "<title>a&lt;b</title>") {
+		t.Fatal("title is not escaped")
+	}
+	if n := strings.Count(s, "</script>"); n != 1 {
+		t.Fatalf("expected one </script>, got %d", n)
+	}
+	// the embedded scene decodes back to the same scene
+	i := strings.Index(s, "var scene = ")
+	if i == -1 {
+		t.Fatal("missing scene")
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(strings.NewReader(s[i+len("var scene = "):])).Decode(&raw); err != nil {
+		t.Fatal(err)
+	}
+	var q Pinhole
+	if err := json.Unmarshal(raw, &q); err != nil {
+		t.Fatal(err)
+	}
+	expect, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(expect, got) {
+		t.Fatalf("expected\n%s\ngot\n%s", expect, got)
+	}
+	if len(q.lines) != len(p.lines) {
+		t.Fatalf("expected %d lines, got %d", len(p.lines), len(q.lines))
+	}
+}
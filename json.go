@@ -0,0 +1,312 @@
+package pinhole
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// The JSON scene format is an object with a "lines" array and an optional
+// "groups" array. Each element of the lines array is one line of the scene,
+// in drawing order:
+//
+//	{"from":[x,y,z],"to":[x,y,z]}                 a line
+//	{"from":[x,y,z],"radius":r}                   a dot, see DrawDot
+//	{"from":[x,y,z],"text":"label","scale":s}     a string, see DrawString
+//	{"from":[x,y,z],"to":[x,y,z],"circle":c,"segment":i}
+//	                                              segment i of circle c
+//
+// Every element may have a "color", either as "#rrggbb" or "#rrggbbaa", or
+// as an array of four alpha-premultiplied 16-bit [r,g,b,a] values when the
+// color cannot be written exactly in hex. The default is black. The "scale"
+// defaults to 1 and, when present on a dot, takes precedence over "radius".
+// A dot of radius 0 has a "scale" of 0.
+// Circles are numbered from 1 and their segments from 0. Segments of open
+// polylines drawn like circles, such as arcs, have "open":true. The "nocaps"
+// flag defaults to true for circle segments and false otherwise. A flat
+// arrowhead drawn by DrawArrow has "head":true, its tip at "to" and its size
+// in "scale". Circle segments cannot have "text" or "head".
+//
+// Elements of the groups array are the groups created by Begin or
+// BeginGroup, numbered from 1:
+//
+//	{"name":"label","parent":g}
+//
+// A line belongs to the group numbered by its "group" field and a group to
+// its "parent" group. Parents come before their children. Both fields are
+// omitted when there is no group.
+
+type jsonLine struct {
+	From    [3]float64      `json:"from"`
+	To      *[3]float64     `json:"to,omitempty"`
+	Color   json.RawMessage `json:"color,omitempty"`
+	Radius  float64         `json:"radius,omitempty"`
+	Text    string          `json:"text,omitempty"`
+	Scale   *float64        `json:"scale,omitempty"`
+	Circle  int             `json:"circle,omitempty"`
+	Segment int             `json:"segment,omitempty"`
+	Open    bool            `json:"open,omitempty"`
+	Head    bool            `json:"head,omitempty"`
+	NoCaps  *bool           `json:"nocaps,omitempty"`
+	Group   int             `json:"group,omitempty"`
+}
+
+type jsonGroup struct {
+	Name   string `json:"name"`
+	Parent int    `json:"parent,omitempty"`
+}
+
+type jsonScene struct {
+	Groups []jsonGroup `json:"groups,omitempty"`
+	Lines  []jsonLine  `json:"lines"`
+}
+
+// WriteJSON writes the scene to w using the JSON scene format.
+func (p *Pinhole) WriteJSON(w io.Writer) error {
+	// number the groups, parents first
+	groups := make(map[*group]int)
+	var jgroups []jsonGroup
+	var number func(g *group) int
+	number = func(g *group) int {
+		if g == nil {
+			return 0
+		}
+		if id, ok := groups[g]; ok {
+			return id
+		}
+		parent := number(g.parent)
+		jgroups = append(jgroups, jsonGroup{Name: g.name, Parent: parent})
+		groups[g] = len(jgroups)
+		return len(jgroups)
+	}
+	for _, l := range p.lines {
+		number(l.group)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("{")
+	if len(jgroups) > 0 {
+		data, err := json.Marshal(jgroups)
+		if err != nil {
+			return err
+		}
+		bw.WriteString("\"groups\":")
+		bw.Write(data)
+		bw.WriteString(",\n")
+	}
+	bw.WriteString("\"lines\":[")
+	circles := make(map[*line]int)
+	segments := make(map[*line]int)
+	for i, l := range p.lines {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString("\n")
+		jl := encodeJSONLine(l, circles, segments)
+		jl.Group = groups[l.group]
+		data, err := json.Marshal(jl)
+		if err != nil {
+			return err
+		}
+		bw.Write(data)
+	}
+	bw.WriteString("\n]}\n")
+	return bw.Flush()
+}
+
+// MarshalJSON returns the scene in the JSON scene format.
+func (p *Pinhole) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.WriteJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON replaces the scene with one in the JSON scene format.
+func (p *Pinhole) UnmarshalJSON(data []byte) error {
+	var scene jsonScene
+	if err := json.Unmarshal(data, &scene); err != nil {
+		return err
+	}
+	var np Pinhole
+	if err := np.addJSONScene(&scene); err != nil {
+		return err
+	}
+	*p = np
+	return nil
+}
+
+// encodeJSONLine returns the JSON element for l. The circles are numbered in
+// circles, keyed by their first segment, and the segments of every circle
+// are numbered in segments when the circle is first seen.
+func encodeJSONLine(l *line, circles, segments map[*line]int) jsonLine {
+	jl := jsonLine{From: [3]float64{l.x1, l.y1, l.z1}}
+	point := l.x1 == l.x2 && l.y1 == l.y2 && l.z1 == l.z2
+	if !point {
+		jl.To = &[3]float64{l.x2, l.y2, l.z2}
+	}
+	if !isDefaultColor(l.color) {
+		jl.Color = encodeJSONColor(l.color)
+	}
+	scale := l.scale
+	if point && l.str == "" {
+		jl.Radius = l.scale / (10 / 0.1)
+		if jl.Radius*(10/0.1) != l.scale || l.scale == 0 {
+			jl.Scale = &scale
+		}
+	} else if l.scale != 1 {
+		jl.Scale = &scale
+	}
+	if l.nocaps != l.circle {
+		nocaps := l.nocaps
+		jl.NoCaps = &nocaps
+	}
+	jl.Text = l.str
+	jl.Head = l.head
+	if l.circle {
+		id, ok := circles[l.cfirst]
+		if !ok {
+			id = len(circles) + 1
+			circles[l.cfirst] = id
+			i := 0
+			for seg := l.cfirst; seg != nil; seg = seg.cnext {
+				segments[seg] = i
+				i++
+			}
+		}
+		jl.Circle = id
+		jl.Open = l.copen
+		jl.Segment = segments[l]
+	}
+	return jl
+}
+
+func isDefaultColor(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	return r == 0 && g == 0 && b == 0 && a == 0xffff
+}
+
+// encodeJSONColor writes the color as hex when that is lossless, otherwise
+// as the premultiplied 16-bit components.
+func encodeJSONColor(c color.Color) json.RawMessage {
+	r, g, b, a := c.RGBA()
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if r2, g2, b2, a2 := n.RGBA(); r2 == r && g2 == g && b2 == b && a2 == a {
+		s := fmt.Sprintf("\"#%02x%02x%02x", n.R, n.G, n.B)
+		if n.A != 0xff {
+			s += fmt.Sprintf("%02x", n.A)
+		}
+		return json.RawMessage(s + "\"")
+	}
+	return json.RawMessage(fmt.Sprintf("[%d,%d,%d,%d]", r, g, b, a))
+}
+
+func decodeJSONColor(data json.RawMessage) (color.Color, error) {
+	if len(data) == 0 {
+		return color.Black, nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		if (len(s) != 7 && len(s) != 9) || s[0] != '#' {
+			return nil, fmt.Errorf("invalid color: %s", s)
+		}
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid color: %s", s)
+		}
+		if len(s) == 7 {
+			v = v<<8 | 0xff
+		}
+		return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+	}
+	var c [4]uint16
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid color: %s", string(data))
+	}
+	return color.RGBA64{c[0], c[1], c[2], c[3]}, nil
+}
+
+// LoadJSON reads a scene in the JSON scene format from r and adds its lines
+// to the current scene.
+func (p *Pinhole) LoadJSON(r io.Reader) error {
+	var scene jsonScene
+	if err := json.NewDecoder(r).Decode(&scene); err != nil {
+		return err
+	}
+	return p.addJSONScene(&scene)
+}
+
+// addJSONScene adds the groups and lines of the scene. Top level groups and
+// lines without a group are placed in the current group.
+func (p *Pinhole) addJSONScene(scene *jsonScene) error {
+	groups := make([]*group, len(scene.Groups)+1)
+	groups[0] = p.group()
+	for i, jg := range scene.Groups {
+		if jg.Parent < 0 || jg.Parent > i {
+			return fmt.Errorf("group %d: invalid parent: %d", i+1, jg.Parent)
+		}
+		groups[i+1] = &group{name: jg.Name, parent: groups[jg.Parent]}
+	}
+	circles := make(map[int][]*line)
+	segments := make(map[*line]int)
+	for i, jl := range scene.Lines {
+		c, err := decodeJSONColor(jl.Color)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", i, err.Error())
+		}
+		if jl.Group < 0 || jl.Group >= len(groups) {
+			return fmt.Errorf("line %d: invalid group: %d", i, jl.Group)
+		}
+		if jl.Circle != 0 && (jl.Text != "" || jl.Head) {
+			return fmt.Errorf("line %d: circle segment with text or head", i)
+		}
+		to := jl.From
+		if jl.To != nil {
+			to = *jl.To
+		}
+		p.DrawLine(jl.From[0], jl.From[1], jl.From[2], to[0], to[1], to[2])
+		l := p.lines[len(p.lines)-1]
+		l.color = c
+		l.str = jl.Text
+		l.head = jl.Head
+		l.group = groups[jl.Group]
+		if jl.Scale != nil {
+			l.scale = *jl.Scale
+		} else if jl.Radius != 0 {
+			l.scale = jl.Radius * (10 / 0.1)
+		}
+		if jl.Circle != 0 {
+			l.circle = true
+			l.copen = jl.Open
+			circles[jl.Circle] = append(circles[jl.Circle], l)
+			segments[l] = jl.Segment
+		}
+		l.nocaps = l.circle
+		if jl.NoCaps != nil {
+			l.nocaps = *jl.NoCaps
+		}
+	}
+	for _, segs := range circles {
+		sort.Slice(segs, func(i, j int) bool {
+			return segments[segs[i]] < segments[segs[j]]
+		})
+		for i, seg := range segs {
+			if segments[seg] != i {
+				return errors.New("circle segments are not numbered in sequence")
+			}
+			seg.cfirst = segs[0]
+			if i > 0 {
+				seg.cprev = segs[i-1]
+				segs[i-1].cnext = seg
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package pinhole
+
+import (
+	"bytes"
+	"encoding/json"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+// jsonTestScene returns a scene with every kind of element.
+func jsonTestScene() *Pinhole {
+	p := New()
+	p.BeginGroup("shapes")
+	p.DrawCircle(-0.4, 0.3, 0, 0.2)
+	p.DrawArc(0.3, 0.3, 0.1, 0.2, 0, math.Pi, nil)
+	p.Colorize(color.RGBA{0xff, 0, 0, 0xff})
+	p.BeginGroup("marks")
+	p.DrawDot(0, 0, 0, 0.05)
+	p.DrawString(-0.3, -0.3, 0, "label")
+	p.Colorize(color.RGBA{0, 0x80, 0, 0x80})
+	p.End()
+	p.End()
+	p.DrawArrow(-0.5, -0.5, 0.2, 0.5, -0.2, -0.2, &ArrowOptions{Head: FlatHead, FlatSize: 6, Double: true})
+	p.DrawLine(-0.5, 0.5, 0, 0.5, 0.5, 0.3)
+	return p
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	p := jsonTestScene()
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q Pinhole
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatal(err)
+	}
+	data2, err := json.Marshal(&q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Fatalf("expected\n%s\ngot\n%s", data, data2)
+	}
+	img1 := p.Image(200, 200, nil)
+	img2 := q.Image(200, 200, nil)
+	if !bytes.Equal(img1.Pix, img2.Pix) {
+		t.Fatal("images differ")
+	}
+}
+
+func TestJSONInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		json string
+		err  string
+	}{
+		{"circle text", `{"lines":[{"from":[0,0,0],"to":[1,0,0],"circle":1,"text":"a"}]}`, "circle segment"},
+		{"circle head", `{"lines":[{"from":[0,0,0],"to":[1,0,0],"circle":1,"head":true}]}`, "circle segment"},
+		{"group", `{"lines":[{"from":[0,0,0],"group":1}]}`, "invalid group"},
+		{"parent", `{"groups":[{"name":"a","parent":1}],"lines":[]}`, "invalid parent"},
+		{"sequence", `{"lines":[{"from":[0,0,0],"to":[1,0,0],"circle":1,"segment":1}]}`, "sequence"},
+	} {
+		err := New().LoadJSON(strings.NewReader(tc.json))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected %q error, got %v", tc.name, tc.err, err)
+		}
+	}
+}
+
+func TestJSONZeroScale(t *testing.T) {
+	p := New()
+	p.DrawDot(0, 0, 0, 0)
+	p.DrawLine(-0.5, 0, 0, 0.5, 0, 0)
+	p.lines[1].scale = 0
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q Pinhole
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range q.lines {
+		if l.scale != 0 {
+			t.Fatalf("line %d: expected scale 0, got %v", i, l.scale)
+		}
+	}
+}
+
+func TestJSONSegments(t *testing.T) {
+	p := New()
+	p.DrawCircleWithOptions(0, 0, 0, 0.5, &CircleOptions{Segments: 100})
+	p.DrawArc(0, 0, 0, 0.3, 0, math.Pi, nil)
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scene jsonScene
+	if err := json.Unmarshal(data, &scene); err != nil {
+		t.Fatal(err)
+	}
+	next := make(map[int]int)
+	for i, jl := range scene.Lines {
+		if jl.Segment != next[jl.Circle] {
+			t.Fatalf("line %d: expected segment %d of circle %d, got %d",
+				i, next[jl.Circle], jl.Circle, jl.Segment)
+		}
+		next[jl.Circle]++
+	}
+	if len(next) != 2 || next[1] != 100 {
+		t.Fatalf("expected two circles with 100 segments in the first, got %v", next)
+	}
+}
@@ -0,0 +1,224 @@
+package pinhole
+
+import (
+	"image/color"
+	"math"
+)
+
+// MeshOptions control how the polygons of a model file are turned into
+// lines.
+type MeshOptions struct {
+	// WeldEdges draws each unique edge once, no matter how many polygons
+	// share it. The first color and group seen for an edge are used.
+	WeldEdges bool
+	// WeldTolerance is the distance under which two vertices are treated as
+	// the same point when welding edges. Zero only welds identical vertices.
+	WeldTolerance float64
+	// SuppressCoplanar skips edges that are shared by two polygons lying in
+	// the same plane, such as the diagonals of triangulated flat faces. It
+	// implies WeldEdges. The lines are drawn after the whole mesh is read.
+	SuppressCoplanar bool
+	// CoplanarAngle is the largest angle, in radians, between the normals of
+	// two polygons for them to be treated as coplanar. The winding of the
+	// polygons is ignored. Zero means 0.001.
+	CoplanarAngle float64
+	// Stats is filled with statistics about the import when not nil.
+	Stats *MeshStats
+}
+
+type MeshStats struct {
+	Edges          int // number of lines drawn
+	MergedEdges    int // number of duplicate edges that were not drawn
+	WeldedVertices int // number of vertices moved onto a nearby vertex
+	CoplanarEdges  int // number of edges between coplanar polygons not drawn
+}
+
+// meshEdge is an edge that is waiting for all of its polygons to be read.
+type meshEdge struct {
+	i1, i2  int
+	color   color.Color
+	group   *group
+	normals [][3]float64
+	open    bool // part of a polyline rather than a polygon
+}
+
+// meshBuilder draws the polygons of a mesh as lines, optionally welding
+// shared vertices and edges.
+type meshBuilder struct {
+	p     *Pinhole
+	opts  MeshOptions
+	stats MeshStats
+
+	exact map[[3]float64]int  // vertex ids by exact position
+	cells map[[3]int64][]int  // vertex ids by tolerance cell
+	verts [][3]float64        // vertex positions by id
+	edges map[[2]int]struct{} // drawn edges by vertex ids
+
+	pending []*meshEdge          // edges in the order they were first seen
+	shared  map[[2]int]*meshEdge // pending edges by vertex ids
+}
+
+func newMeshBuilder(p *Pinhole, opts *MeshOptions) *meshBuilder {
+	b := &meshBuilder{p: p}
+	if opts != nil {
+		b.opts = *opts
+	}
+	if b.opts.SuppressCoplanar {
+		b.opts.WeldEdges = true
+		b.shared = make(map[[2]int]*meshEdge)
+	}
+	if b.opts.WeldEdges {
+		b.exact = make(map[[3]float64]int)
+		b.cells = make(map[[3]int64][]int)
+		b.edges = make(map[[2]int]struct{})
+	}
+	return b
+}
+
+// polyline draws lines between each of the points, joining the last point
+// to the first when closed.
+func (b *meshBuilder) polyline(points [][3]float64, closed bool, c color.Color) {
+	if len(points) < 2 || (closed && len(points) < 3) {
+		return
+	}
+	var normal *[3]float64
+	if closed && b.opts.SuppressCoplanar {
+		n := polygonNormal(points)
+		normal = &n
+	}
+	for i := 0; i < len(points)-1; i++ {
+		b.edge(points[i], points[i+1], c, normal)
+	}
+	if closed {
+		b.edge(points[len(points)-1], points[0], c, normal)
+	}
+}
+
+func (b *meshBuilder) edge(p1, p2 [3]float64, c color.Color, normal *[3]float64) {
+	if b.opts.SuppressCoplanar {
+		i1, i2 := b.vertex(p1), b.vertex(p2)
+		if i1 == i2 {
+			return
+		}
+		key := [2]int{i1, i2}
+		if i2 < i1 {
+			key = [2]int{i2, i1}
+		}
+		e := b.shared[key]
+		if e == nil {
+			e = &meshEdge{i1: i1, i2: i2, color: c, group: b.p.group()}
+			b.shared[key] = e
+			b.pending = append(b.pending, e)
+		} else {
+			b.stats.MergedEdges++
+		}
+		if normal == nil {
+			e.open = true
+		} else {
+			e.normals = append(e.normals, *normal)
+		}
+		return
+	}
+	if b.opts.WeldEdges {
+		i1, i2 := b.vertex(p1), b.vertex(p2)
+		if i1 == i2 {
+			return
+		}
+		key := [2]int{i1, i2}
+		if i2 < i1 {
+			key = [2]int{i2, i1}
+		}
+		if _, ok := b.edges[key]; ok {
+			b.stats.MergedEdges++
+			return
+		}
+		b.edges[key] = struct{}{}
+		p1, p2 = b.verts[i1], b.verts[i2]
+	}
+	b.p.DrawLine(p1[0], p1[1], p1[2], p2[0], p2[1], p2[2])
+	b.p.lines[len(b.p.lines)-1].color = c
+	b.stats.Edges++
+}
+
+// vertex returns the id of the welded vertex for the point.
+func (b *meshBuilder) vertex(pt [3]float64) int {
+	if id, ok := b.exact[pt]; ok {
+		return id
+	}
+	tol := b.opts.WeldTolerance
+	var cell [3]int64
+	if tol > 0 {
+		for i := 0; i < 3; i++ {
+			cell[i] = int64(math.Floor(pt[i] / tol))
+		}
+		// search the neighboring cells for a vertex within the tolerance
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for dz := int64(-1); dz <= 1; dz++ {
+					ncell := [3]int64{cell[0] + dx, cell[1] + dy, cell[2] + dz}
+					for _, id := range b.cells[ncell] {
+						v := b.verts[id]
+						ddx, ddy, ddz := v[0]-pt[0], v[1]-pt[1], v[2]-pt[2]
+						if ddx*ddx+ddy*ddy+ddz*ddz <= tol*tol {
+							b.exact[pt] = id
+							b.stats.WeldedVertices++
+							return id
+						}
+					}
+				}
+			}
+		}
+	}
+	id := len(b.verts)
+	b.verts = append(b.verts, pt)
+	b.exact[pt] = id
+	if tol > 0 {
+		b.cells[cell] = append(b.cells[cell], id)
+	}
+	return id
+}
+
+// finish draws the pending edges and reports the import statistics.
+func (b *meshBuilder) finish() {
+	angle := b.opts.CoplanarAngle
+	if angle <= 0 {
+		angle = 0.001
+	}
+	minDot := math.Cos(angle)
+	for _, e := range b.pending {
+		if !e.open && len(e.normals) == 2 {
+			n1, n2 := e.normals[0], e.normals[1]
+			if math.Abs(n1[0]*n2[0]+n1[1]*n2[1]+n1[2]*n2[2]) >= minDot {
+				b.stats.MergedEdges--
+				b.stats.CoplanarEdges++
+				continue
+			}
+		}
+		p1, p2 := b.verts[e.i1], b.verts[e.i2]
+		b.p.DrawLine(p1[0], p1[1], p1[2], p2[0], p2[1], p2[2])
+		l := b.p.lines[len(b.p.lines)-1]
+		l.color = e.color
+		l.group = e.group
+		b.stats.Edges++
+	}
+	b.pending = nil
+	if b.opts.Stats != nil {
+		*b.opts.Stats = b.stats
+	}
+}
+
+// polygonNormal returns the unit normal of the polygon using Newell's method.
+func polygonNormal(points [][3]float64) [3]float64 {
+	var n [3]float64
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		n[0] += (a[1] - b[1]) * (a[2] + b[2])
+		n[1] += (a[2] - b[2]) * (a[0] + b[0])
+		n[2] += (a[0] - b[0]) * (a[1] + b[1])
+	}
+	l := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])
+	if l == 0 {
+		return n
+	}
+	return [3]float64{n[0] / l, n[1] / l, n[2] / l}
+}
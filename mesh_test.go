@@ -0,0 +1,65 @@
+package pinhole
+
+import (
+	"strings"
+	"testing"
+)
+
+const meshCube = `v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 0 0 1
+v 1 0 1
+v 1 1 1
+v 0 1 1
+f 1 2 3 4
+f 5 8 7 6
+f 1 5 6 2
+f 2 6 7 3
+f 3 7 8 4
+f 4 8 5 1
+`
+
+// meshSquare is a square made of two triangles and a second copy of one of
+// its corners that is slightly off.
+const meshSquare = `v 0 0 0
+v 1 0 0
+v 1 1 0
+v 0 1 0
+v 1.0001 1 0
+f 1 2 3
+f 1 5 4
+`
+
+func TestMeshStats(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		obj   string
+		opts  MeshOptions
+		stats MeshStats
+	}{
+		{"cube", meshCube, MeshOptions{}, MeshStats{Edges: 24}},
+		{"cube welded", meshCube, MeshOptions{WeldEdges: true}, MeshStats{Edges: 12, MergedEdges: 12}},
+		{"square", meshSquare, MeshOptions{WeldEdges: true}, MeshStats{Edges: 6}},
+		{"square tolerance", meshSquare, MeshOptions{WeldEdges: true, WeldTolerance: 0.001},
+			MeshStats{Edges: 5, MergedEdges: 1, WeldedVertices: 1}},
+		{"square coplanar", meshSquare, MeshOptions{SuppressCoplanar: true, WeldTolerance: 0.001},
+			MeshStats{Edges: 4, WeldedVertices: 1, CoplanarEdges: 1}},
+		{"cube coplanar", meshCube, MeshOptions{SuppressCoplanar: true}, MeshStats{Edges: 12, MergedEdges: 12}},
+	} {
+		var stats MeshStats
+		opts := &ObjOptions{MeshOptions: tc.opts}
+		opts.Stats = &stats
+		p := New()
+		if err := p.LoadObjWithOptions(strings.NewReader(tc.obj), opts); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if stats != tc.stats {
+			t.Fatalf("%s: expected %+v, got %+v", tc.name, tc.stats, stats)
+		}
+		if len(p.lines) != stats.Edges {
+			t.Fatalf("%s: expected %d lines, got %d", tc.name, stats.Edges, len(p.lines))
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package pinhole
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type ObjOptions struct {
+	// MaterialResolver opens the material library files named by mtllib
+	// statements. The diffuse color (Kd) of each material is used as the
+	// line color for elements following a usemtl statement. Returning a nil
+	// reader and a nil error skips the library. When MaterialResolver is nil
+	// materials are ignored.
+	MaterialResolver func(name string) (io.ReadCloser, error)
+	// Progress is called every ProgressInterval lines, and once more when
+	// loading is complete, with the number of lines and bytes read so far.
+	Progress func(lines int, bytes int64)
+	// ProgressInterval is the number of lines between calls to Progress.
+	// Zero means every 10000 lines.
+	ProgressInterval int
+	// ErrorHandler is called for every line that cannot be parsed. Returning
+	// nil skips the line and continues loading, otherwise loading stops and
+	// the returned error is passed on. When ErrorHandler is nil the first
+	// parse error stops loading.
+	ErrorHandler func(err *ObjError) error
+	// DotRadius is the radius of the dots drawn for point elements. Zero
+	// means 0.02.
+	DotRadius float64
+
+	MeshOptions
+}
+
+var DefaultObjOptions = &ObjOptions{
+	DotRadius: 0.02,
+}
+
+// ObjError is a parse error for a single line of an OBJ file.
+type ObjError struct {
+	Line int
+	Err  error
+}
+
+func (e *ObjError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+func (e *ObjError) Unwrap() error {
+	return e.Err
+}
+
+func (p *Pinhole) LoadObj(r io.Reader) error {
+	return p.LoadObjWithOptions(r, nil)
+}
+
+// LoadObjWithOptions reads Wavefront OBJ data from r. The data is streamed
+// one line at a time and each element is drawn as soon as it's read. Faces
+// are drawn as closed loops, line elements as polylines and the vertices of
+// point elements as dots. Object and group
+// names are mapped to named groups, see BeginGroup. Edges shared by faces
+// can be drawn once using the MeshOptions.
+func (p *Pinhole) LoadObjWithOptions(r io.Reader, opts *ObjOptions) error {
+	if opts == nil {
+		opts = DefaultObjOptions
+	}
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = 10000
+	}
+	radius := opts.DotRadius
+	if radius <= 0 {
+		radius = 0.02
+	}
+	var verts [][3]float64
+	var points [][3]float64
+	materials := make(map[string]color.Color)
+	var ccolor color.Color = color.Black
+	var inObject, inGroup bool
+	mb := newMeshBuilder(p, &opts.MeshOptions)
+	defer func() {
+		mb.finish()
+		if inGroup {
+			p.End()
+		}
+		if inObject {
+			p.End()
+		}
+	}()
+	parse := func(line string) error {
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return nil
+		}
+		args := fields[1:]
+		switch fields[0] {
+		case "v":
+			if len(args) < 3 {
+				return errors.New("not enough vertex coordinates")
+			}
+			var v [3]float64
+			for j := 0; j < 3; j++ {
+				var err error
+				if v[j], err = strconv.ParseFloat(args[j], 64); err != nil {
+					return err
+				}
+			}
+			verts = append(verts, v)
+		case "f", "l", "p":
+			points = points[:0]
+			for _, arg := range args {
+				if i := strings.IndexByte(arg, '/'); i != -1 {
+					arg = arg[:i]
+				}
+				idx, err := strconv.ParseInt(arg, 10, 64)
+				if err != nil {
+					return err
+				}
+				if idx < 0 {
+					idx += int64(len(verts)) + 1
+				}
+				if idx <= 0 || idx > int64(len(verts)) {
+					return fmt.Errorf("invalid vert index: %s", arg)
+				}
+				points = append(points, verts[idx-1])
+			}
+			if fields[0] != "p" {
+				mb.polyline(points, fields[0] == "f", ccolor)
+				break
+			}
+			for _, v := range points {
+				p.DrawDot(v[0], v[1], v[2], radius)
+				p.lines[len(p.lines)-1].color = ccolor
+			}
+		case "o":
+			if inGroup {
+				p.End()
+				inGroup = false
+			}
+			if inObject {
+				p.End()
+			}
+			p.BeginGroup(strings.Join(args, " "))
+			inObject = true
+		case "g":
+			if inGroup {
+				p.End()
+				inGroup = false
+			}
+			if len(args) > 0 {
+				p.BeginGroup(strings.Join(args, " "))
+				inGroup = true
+			}
+		case "mtllib":
+			if opts.MaterialResolver == nil {
+				return nil
+			}
+			for _, name := range args {
+				if err := loadMtl(opts.MaterialResolver, name, materials); err != nil {
+					return err
+				}
+			}
+		case "usemtl":
+			ccolor = color.Black
+			if len(args) > 0 {
+				if c, ok := materials[args[0]]; ok {
+					ccolor = c
+				}
+			}
+		}
+		return nil
+	}
+	br := bufio.NewReader(r)
+	var ln int
+	var nbytes int64
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) == 0 && err == io.EOF {
+			break
+		}
+		ln++
+		nbytes += int64(len(line))
+		if perr := parse(line); perr != nil {
+			oerr := &ObjError{Line: ln, Err: perr}
+			if opts.ErrorHandler == nil {
+				return oerr
+			}
+			if herr := opts.ErrorHandler(oerr); herr != nil {
+				return herr
+			}
+		}
+		if opts.Progress != nil && ln%interval == 0 {
+			opts.Progress(ln, nbytes)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(ln, nbytes)
+	}
+	return nil
+}
+
+// loadMtl reads the diffuse colors from a material library into materials.
+func loadMtl(resolve func(name string) (io.ReadCloser, error), name string,
+	materials map[string]color.Color,
+) error {
+	rc, err := resolve(name)
+	if err != nil {
+		return err
+	}
+	if rc == nil {
+		return nil
+	}
+	defer rc.Close()
+	var cname string
+	var c color.NRGBA
+	flush := func() {
+		if cname != "" {
+			materials[cname] = c
+		}
+	}
+	s := bufio.NewScanner(rc)
+	s.Buffer(nil, 1<<20)
+	for ln := 0; s.Scan(); ln++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "newmtl":
+			flush()
+			cname = strings.Join(fields[1:], " ")
+			c = color.NRGBA{0, 0, 0, 0xff}
+		case "Kd":
+			if len(fields) < 4 {
+				return fmt.Errorf("%s: line %d: not enough color components", name, ln+1)
+			}
+			var rgb [3]float64
+			for j := 0; j < 3; j++ {
+				if rgb[j], err = strconv.ParseFloat(fields[j+1], 64); err != nil {
+					return fmt.Errorf("%s: line %d: %s", name, ln+1, err.Error())
+				}
+			}
+			c.R, c.G, c.B = unitByte(rgb[0]), unitByte(rgb[1]), unitByte(rgb[2])
+		case "d":
+			if len(fields) < 2 {
+				continue
+			}
+			d, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return fmt.Errorf("%s: line %d: %s", name, ln+1, err.Error())
+			}
+			c.A = unitByte(d)
+		}
+	}
+	flush()
+	return s.Err()
+}
+
+// unitByte converts a 0.0 - 1.0 value to a 0 - 255 byte.
+func unitByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 0xff
+	}
+	return uint8(v*0xff + 0.5)
+}
@@ -0,0 +1,187 @@
+package pinhole
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lineNames returns the group name of each line.
+func lineNames(p *Pinhole) []string {
+	names := make([]string, len(p.lines))
+	for i, l := range p.lines {
+		for g := l.group; g != nil; g = g.parent {
+			if g.name != "" {
+				names[i] = g.name + "/" + names[i]
+			}
+		}
+		names[i] = strings.TrimSuffix(names[i], "/")
+	}
+	return names
+}
+
+func TestObj(t *testing.T) {
+	const verts = "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\n"
+	for _, tc := range []struct {
+		name  string
+		obj   string
+		lines int
+		err   string
+	}{
+		{"face", verts + "f 1 2 3 4\n", 4, ""},
+		{"texture and normal", verts + "f 1/1/1 2/2/2 3//3\n", 3, ""},
+		{"negative", verts + "f -4 -3 -2\n", 3, ""},
+		{"negative after more", verts + "f -1 -2 -3\nv 2 2 2\nf -1 -2 -3\n", 6, ""},
+		{"polyline", verts + "l 1 2 3 4\n", 3, ""},
+		{"zero index", verts + "f 0 1 2\n", 0, "invalid vert index: 0"},
+		{"past end", verts + "l 1 5\n", 0, "invalid vert index: 5"},
+		{"before start", verts + "l -5 1\n", 0, "invalid vert index: -5"},
+		{"bad vertex", "v 0 0\n", 0, "not enough vertex coordinates"},
+	} {
+		p := New()
+		err := p.LoadObj(strings.NewReader(tc.obj))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("%s: expected %q error, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(p.lines) != tc.lines {
+			t.Fatalf("%s: expected %d lines, got %d", tc.name, tc.lines, len(p.lines))
+		}
+	}
+}
+
+func TestObjGroups(t *testing.T) {
+	p := New()
+	err := p.LoadObj(strings.NewReader(`v 0 0 0
+v 1 0 0
+l 1 2
+o box
+l 1 2
+g top
+l 1 2
+g
+l 1 2
+o other thing
+g side
+l 1 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := strings.Join(lineNames(p), ",")
+	if names != ",box,box/top,box,other thing/side" {
+		t.Fatalf("got %q", names)
+	}
+}
+
+func TestObjMaterials(t *testing.T) {
+	opts := &ObjOptions{
+		MaterialResolver: func(name string) (io.ReadCloser, error) {
+			if name != "colors.mtl" {
+				return nil, nil
+			}
+			return ioutil.NopCloser(strings.NewReader(
+				"newmtl red\nKd 1 0 0\nnewmtl blue\nKd 0 0 1\n")), nil
+		},
+	}
+	p := New()
+	err := p.LoadObjWithOptions(strings.NewReader(`mtllib colors.mtl missing.mtl
+v 0 0 0
+v 1 0 0
+l 1 2
+usemtl red
+l 1 2
+usemtl blue
+l 1 2
+usemtl unknown
+l 1 2
+`), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []color.Color{
+		color.Black,
+		color.RGBA{0xff, 0, 0, 0xff},
+		color.RGBA{0, 0, 0xff, 0xff},
+		color.Black,
+	}
+	if len(p.lines) != len(expect) {
+		t.Fatalf("expected %d lines, got %d", len(expect), len(p.lines))
+	}
+	for i, c := range expect {
+		r1, g1, b1, a1 := p.lines[i].color.RGBA()
+		r2, g2, b2, a2 := c.RGBA()
+		if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+			t.Fatalf("line %d: expected %v, got %v", i, c, p.lines[i].color)
+		}
+	}
+}
+
+func TestObjErrors(t *testing.T) {
+	const obj = "v 0 0 0\nv 1 0 0\nv x 0 0\nl 1 2\nl 1 9\nl 2 1"
+	err := New().LoadObj(strings.NewReader(obj))
+	oerr, ok := err.(*ObjError)
+	if !ok || oerr.Line != 3 {
+		t.Fatalf("expected an error on line 3, got %v", err)
+	}
+	if _, ok := errors.Unwrap(err).(*strconv.NumError); !ok {
+		t.Fatalf("expected a *strconv.NumError, got %T", errors.Unwrap(err))
+	}
+
+	// skip the bad lines
+	var lines []int
+	p := New()
+	err = p.LoadObjWithOptions(strings.NewReader(obj), &ObjOptions{
+		ErrorHandler: func(err *ObjError) error {
+			lines = append(lines, err.Line)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(lines) != "[3 5]" || len(p.lines) != 2 {
+		t.Fatalf("expected errors on lines [3 5] and 2 lines, got %v and %d", lines, len(p.lines))
+	}
+
+	// stop at the second bad line
+	stop := errors.New("stop")
+	err = New().LoadObjWithOptions(strings.NewReader(obj), &ObjOptions{
+		ErrorHandler: func(err *ObjError) error {
+			if err.Line == 5 {
+				return stop
+			}
+			return nil
+		},
+	})
+	if err != stop {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+}
+
+func TestObjProgress(t *testing.T) {
+	obj := strings.Repeat("v 0 0 0\n", 25) + "l 1 2"
+	var calls []string
+	err := New().LoadObjWithOptions(strings.NewReader(obj), &ObjOptions{
+		ProgressInterval: 10,
+		Progress: func(lines int, bytes int64) {
+			calls = append(calls, fmt.Sprintf("%d:%d", lines, bytes))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := strings.Join(calls, ","); s != "10:80,20:160,26:205" {
+		t.Fatalf("got %s", s)
+	}
+}
@@ -1,17 +1,11 @@
 package pinhole
 
 import (
-	"fmt"
 	"image"
 	"image/color"
-	"image/png"
-	"io"
-	"io/ioutil"
 	"math"
 	"os"
 	"sort"
-	"strconv"
-	"strings"
 
 	"golang.org/x/image/font/gofont/goregular"
 
@@ -38,9 +32,12 @@ type line struct {
 	str        string
 	scale      float64
 	circle     bool
+	copen      bool // the circle segments do not join at the ends
+	head       bool // a flat arrowhead at x2, y2, z2, see DrawArrow
 	cfirst     *line
 	cprev      *line
 	cnext      *line
+	group      *group
 
 	drawcoords *fourcorners
 }
@@ -73,21 +70,44 @@ func (l *line) Center() []float64 {
 	}
 }
 
+// group is a Begin/End block. Every line remembers the innermost group it
+// was drawn in.
+type group struct {
+	name   string
+	parent *group
+}
+
 type Pinhole struct {
-	lines []*line
-	stack []int
+	lines  []*line
+	stack  []int
+	groups []*group
 }
 
 func New() *Pinhole {
 	return &Pinhole{}
 }
 func (p *Pinhole) Begin() {
+	p.BeginGroup("")
+}
+
+// BeginGroup is like Begin but gives the group a name. The name is kept with
+// the lines drawn in the group.
+func (p *Pinhole) BeginGroup(name string) {
 	p.stack = append(p.stack, len(p.lines))
+	p.groups = append(p.groups, &group{name: name, parent: p.group()})
 }
 func (p *Pinhole) End() {
 	if len(p.stack) > 0 {
 		p.stack = p.stack[:len(p.stack)-1]
+		p.groups = p.groups[:len(p.groups)-1]
+	}
+}
+
+func (p *Pinhole) group() *group {
+	if len(p.groups) > 0 {
+		return p.groups[len(p.groups)-1]
 	}
+	return nil
 }
 func (p *Pinhole) Rotate(x, y, z float64) {
 	var i int
@@ -244,41 +264,58 @@ func (p *Pinhole) DrawLine(x1, y1, z1, x2, y2, z2 float64) {
 		x2: x2, y2: y2, z2: z2,
 		color: color.Black,
 		scale: 1,
+		group: p.group(),
 	}
 	p.lines = append(p.lines, l)
 }
 func (p *Pinhole) DrawCircle(x, y, z float64, radius float64) {
-	var fx, fy, fz float64
-	var lx, ly, lz float64
-	var first, prev *line
-	// we go one beyond the steps because we need to join at the end
-	for i := float64(0); i <= circleSteps; i++ {
-		var dx, dy, dz float64
-		dx, dy = destination(x, y, (math.Pi*2)/circleSteps*i, radius)
-		dz = z
-		if i > 0 {
-			if i == circleSteps {
-				p.DrawLine(lx, ly, lz, fx, fy, fz)
-			} else {
-				p.DrawLine(lx, ly, lz, dx, dy, dz)
-			}
-			line := p.lines[len(p.lines)-1]
-			line.nocaps = true
-			line.circle = true
-			if first == nil {
-				first = line
-			}
-			line.cfirst = first
-			line.cprev = prev
-			if prev != nil {
-				prev.cnext = line
-			}
-			prev = line
+	p.DrawCircleWithOptions(x, y, z, radius, nil)
+}
 
-		} else {
-			fx, fy, fz = dx, dy, dz
+// drawRing draws a closed polyline through the points the way circles are
+// drawn, as segments without caps whose joints are smoothed by Image.
+func (p *Pinhole) drawRing(points [][3]float64) {
+	p.drawChain(points, true)
+}
+
+// drawChain draws a polyline through the points like drawRing. When it is
+// not closed the ends get round caps. Repeated points are skipped because
+// the segments cannot be empty.
+func (p *Pinhole) drawChain(points [][3]float64, closed bool) {
+	var unique [][3]float64
+	for i, pt := range points {
+		if i == 0 || pt != points[i-1] {
+			unique = append(unique, pt)
+		}
+	}
+	if closed && len(unique) > 1 && unique[0] == unique[len(unique)-1] {
+		unique = unique[:len(unique)-1]
+	}
+	if len(unique) < 2 {
+		return
+	}
+	points = unique
+	n := len(points)
+	if !closed {
+		n--
+	}
+	var first, prev *line
+	for i := 0; i < n; i++ {
+		pt, next := points[i], points[(i+1)%len(points)]
+		p.DrawLine(pt[0], pt[1], pt[2], next[0], next[1], next[2])
+		line := p.lines[len(p.lines)-1]
+		line.nocaps = true
+		line.circle = true
+		line.copen = !closed
+		if first == nil {
+			first = line
+		}
+		line.cfirst = first
+		line.cprev = prev
+		if prev != nil {
+			prev.cnext = line
 		}
-		lx, ly, lz = dx, dy, dz
+		prev = line
 	}
 }
 
@@ -353,6 +390,16 @@ func (p *Pinhole) Image(width, height int, opts *ImageOptions) *image.RGBA {
 		}
 		t1 := lineWidthAtZ(z1, focal) * opts.LineWidth * line.scale
 		t2 := lineWidthAtZ(z2, focal) * opts.LineWidth * line.scale
+		if line.head {
+			t := lineWidthAtZ(z2, focal) * opts.LineWidth
+			if tx, ty, lx, ly, rx, ry, ok := arrowhead(px1, py1, px2, py2, t, line.scale); ok {
+				c.MoveTo(tx, ty)
+				c.LineTo(lx, ly)
+				c.LineTo(rx, ry)
+				c.ClosePath()
+			}
+			return nil
+		}
 		if line.str != "" {
 			sz := 10 * t1
 			c.SetFontFace(truetype.NewFace(gof, &truetype.Options{Size: sz}))
@@ -372,6 +419,13 @@ func (p *Pinhole) Image(width, height int, opts *ImageOptions) *image.RGBA {
 			line.circle,
 		)
 	}
+	chainCap := func(x, y, z, scale float64) {
+		t := lineWidthAtZ(z, focal) * opts.LineWidth * scale
+		if caps.insert(x, y, z) && t >= 2 {
+			px, py := projectPoint(x, y, z, fwidth, fheight, focal, opts.Scale)
+			c.DrawCircle(px, py, t/2)
+		}
+	}
 	for _, line := range p.lines {
 		if line.color != ccolor {
 			ccolor = line.color
@@ -401,6 +455,9 @@ func (p *Pinhole) Image(width, height int, opts *ImageOptions) *image.RGBA {
 				for i := 0; i < len(coords); i++ {
 					var line1, line2 *fourcorners
 					if i == 0 {
+						if line.copen {
+							continue
+						}
 						line1 = coords[len(coords)-1]
 					} else {
 						line1 = coords[i-1]
@@ -428,6 +485,16 @@ func (p *Pinhole) Image(width, height int, opts *ImageOptions) *image.RGBA {
 			c.LineTo(line.drawcoords.x4+math.SmallestNonzeroFloat64, line.drawcoords.y4+math.SmallestNonzeroFloat64)
 			c.LineTo(line.drawcoords.x1-math.SmallestNonzeroFloat64, line.drawcoords.y1-math.SmallestNonzeroFloat64)
 			c.ClosePath()
+			if line.copen {
+				// round caps for the ends of the chain
+				c.Fill()
+				if line.cprev == nil {
+					chainCap(line.x1, line.y1, line.z1, line.scale)
+				}
+				if line.cnext == nil {
+					chainCap(line.x2, line.y2, line.z2, line.scale)
+				}
+			}
 		} else {
 			maybeDraw(line)
 		}
@@ -436,6 +503,21 @@ func (p *Pinhole) Image(width, height int, opts *ImageOptions) *image.RGBA {
 	return img
 }
 
+// arrowhead returns the corners of a flat arrowhead that points from x1, y1
+// to x2, y2. The head is size times the line width t long and its tip covers
+// the cap of a line that ends at x2, y2.
+func arrowhead(x1, y1, x2, y2, t, size float64) (tx, ty, lx, ly, rx, ry float64, ok bool) {
+	if x1 == x2 && y1 == y2 {
+		return 0, 0, 0, 0, 0, 0, false
+	}
+	a := lineAngle(x1, y1, x2, y2)
+	tx, ty = destination(x2, y2, a, -t/2)
+	bx, by := destination(tx, ty, a, t*size)
+	lx, ly = destination(bx, by, a+math.Pi/2, t*size*0.4)
+	rx, ry = destination(bx, by, a-math.Pi/2, t*size*0.4)
+	return tx, ty, lx, ly, rx, ry, true
+}
+
 type fourcorners struct {
 	x1, y1, x2, y2, x3, y3, x4, y4 float64
 }
@@ -446,7 +528,7 @@ func drawUnbalancedLineSegment(c *gg.Context,
 	cap1, cap2 bool,
 	circleSegment bool,
 ) *fourcorners {
-	if x1 == x2 && y1 == y2 {
+	if x1 == x2 && y1 == y2 && !circleSegment {
 		c.DrawCircle(x1, y1, t1/2)
 		return nil
 	}
@@ -509,78 +591,17 @@ func onscreen(w, h float64, x1, y1, x2, y2 float64) bool {
 	return true
 }
 
-func (p *Pinhole) LoadObj(r io.Reader) error {
-	var faces [][][3]float64
-	data, err := ioutil.ReadAll(r)
+func (p *Pinhole) SavePNG(path string, width, height int, opts *ImageOptions) error {
+	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	var verts [][3]float64
-	for ln, line := range strings.Split(string(data), "\n") {
-		for {
-			nline := strings.Replace(line, "  ", " ", -1)
-			if len(nline) < len(line) {
-				line = nline
-				continue
-			}
-			break
-		}
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "v ") {
-			parts := strings.Split(line[2:], " ")
-			if len(parts) >= 3 {
-				verts = append(verts, [3]float64{})
-				for j := 0; j < 3; j++ {
-					if verts[len(verts)-1][j], err = strconv.ParseFloat(parts[j], 64); err != nil {
-						return fmt.Errorf("line %d: %s", ln+1, err.Error())
-					}
-				}
-			}
-		} else if strings.HasPrefix(line, "f ") {
-			parts := strings.Split(line[2:], " ")
-			if len(parts) >= 3 {
-				faces = append(faces, [][3]float64{})
-				for _, part := range parts {
-					part = strings.Split(part, "/")[0]
-					idx, err := strconv.ParseUint(part, 10, 64)
-					if err != nil {
-						return fmt.Errorf("line %d: %s", ln+1, err.Error())
-					}
-					if int(idx) > len(verts) {
-						return fmt.Errorf("line %d: invalid vert index: %d", ln+1, idx)
-					}
-					faces[len(faces)-1] = append(faces[len(faces)-1], verts[idx-1])
-				}
-			}
-		}
-	}
-	for _, faces := range faces {
-		var fx, fy, fz float64
-		var lx, ly, lz float64
-		var i int
-		for _, face := range faces {
-			if i == 0 {
-				fx, fy, fz = face[0], face[1], face[2]
-			} else {
-				p.DrawLine(lx, ly, lz, face[0], face[1], face[2])
-			}
-			lx, ly, lz = face[0], face[1], face[2]
-			i++
-		}
-		if i > 1 {
-			p.DrawLine(lx, ly, lz, fx, fy, fz)
-		}
-	}
-	return nil
-}
-
-func (p *Pinhole) SavePNG(path string, width, height int, opts *ImageOptions) error {
-	file, err := os.Create(path)
+	err = p.Encode(file, FormatPNG, width, height, &EncodeOptions{ImageOptions: opts})
 	if err != nil {
+		file.Close()
 		return err
 	}
-	defer file.Close()
-	return png.Encode(file, p.Image(width, height, opts))
+	return file.Close()
 }
 
 // projectPoint projects a 3d point cartesian point to 2d screen coords.
@@ -637,6 +658,43 @@ func rotate(x, y, z float64, q float64, which int) (dx, dy, dz float64) {
 	return
 }
 
+func vadd(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] + b[0], a[1] + b[1], a[2] + b[2]}
+}
+
+func vsub(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func vscale(a [3]float64, s float64) [3]float64 {
+	return [3]float64{a[0] * s, a[1] * s, a[2] * s}
+}
+
+func vdot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func vcross(a, b [3]float64) [3]float64 {
+	return [3]float64{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}
+}
+
+func vlen(a [3]float64) float64 {
+	return math.Sqrt(vdot(a, a))
+}
+
+// vnorm returns a unit length copy of a, or a when it has no length.
+func vnorm(a [3]float64) [3]float64 {
+	l := vlen(a)
+	if l == 0 {
+		return a
+	}
+	return vscale(a, 1/l)
+}
+
 type capItem struct {
 	point [3]float64
 }
@@ -0,0 +1,386 @@
+package pinhole
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type PLYOptions struct {
+	// DotRadius is the radius of the dots drawn for vertices that are not
+	// part of a face or an edge. Zero means 0.02.
+	DotRadius float64
+
+	MeshOptions
+}
+
+var DefaultPLYOptions = &PLYOptions{
+	DotRadius: 0.02,
+}
+
+func (p *Pinhole) LoadPLY(r io.Reader) error {
+	return p.LoadPLYWithOptions(r, nil)
+}
+
+// LoadPLYWithOptions reads an ASCII or binary PLY file from r. Faces and
+// edges are drawn as lines. Vertices that are not part of a face or an edge
+// are drawn as dots using DrawDot, so a file without faces or edges is drawn
+// as a point cloud. The red, green and blue vertex properties are used as the
+// line and dot colors.
+func (p *Pinhole) LoadPLYWithOptions(r io.Reader, opts *PLYOptions) error {
+	if opts == nil {
+		opts = DefaultPLYOptions
+	}
+	radius := opts.DotRadius
+	if radius <= 0 {
+		radius = 0.02
+	}
+	pr := &plyReader{r: bufio.NewReader(r)}
+	elements, err := pr.readHeader()
+	if err != nil {
+		return err
+	}
+	pointCloud := true
+	for _, e := range elements {
+		if (e.name == "face" || e.name == "edge") && e.count > 0 {
+			pointCloud = false
+		}
+	}
+	mb := newMeshBuilder(p, &opts.MeshOptions)
+	defer mb.finish()
+
+	var verts [][3]float64
+	var colors []color.Color
+	var used []bool
+	var values []float64
+	var lists [][]float64
+	var points [][3]float64
+	for _, e := range elements {
+		for i := 0; i < e.count; i++ {
+			if err := pr.readElement(e, &values, &lists); err != nil {
+				return fmt.Errorf("ply: %s %d: %s", e.name, i, err.Error())
+			}
+			switch e.name {
+			case "vertex":
+				var v [3]float64
+				for j, name := range [3]string{"x", "y", "z"} {
+					if k := e.index(name); k != -1 {
+						v[j] = values[k]
+					}
+				}
+				c := e.color(values)
+				if pointCloud {
+					p.DrawDot(v[0], v[1], v[2], radius)
+					if c != nil {
+						p.lines[len(p.lines)-1].color = c
+					}
+					continue
+				}
+				verts = append(verts, v)
+				colors = append(colors, c)
+				used = append(used, false)
+			case "face":
+				k := e.index("vertex_indices")
+				if k == -1 {
+					k = e.index("vertex_index")
+				}
+				if k == -1 {
+					return errors.New("ply: face element has no vertex_indices")
+				}
+				idxs := lists[k]
+				points = points[:0]
+				for _, idx := range idxs {
+					if idx < 0 || int(idx) >= len(verts) {
+						return fmt.Errorf("ply: face %d: invalid vertex index: %v", i, idx)
+					}
+					points = append(points, verts[int(idx)])
+				}
+				if len(points) < 3 {
+					continue
+				}
+				normal := polygonNormal(points)
+				fc := e.color(values)
+				for j := range idxs {
+					i1, i2 := int(idxs[j]), int(idxs[(j+1)%len(idxs)])
+					used[i1] = true
+					c := fc
+					if c == nil {
+						c = blendColors(colors[i1], colors[i2])
+					}
+					mb.edge(verts[i1], verts[i2], c, &normal)
+				}
+			case "edge":
+				k1, k2 := e.index("vertex1"), e.index("vertex2")
+				if k1 == -1 || k2 == -1 {
+					return errors.New("ply: edge element has no vertex1 or vertex2")
+				}
+				i1, i2 := values[k1], values[k2]
+				if i1 < 0 || int(i1) >= len(verts) || i2 < 0 || int(i2) >= len(verts) {
+					return fmt.Errorf("ply: edge %d: invalid vertex index", i)
+				}
+				c := e.color(values)
+				if c == nil {
+					c = blendColors(colors[int(i1)], colors[int(i2)])
+				}
+				mb.edge(verts[int(i1)], verts[int(i2)], c, nil)
+				used[int(i1)], used[int(i2)] = true, true
+			}
+		}
+	}
+	for i, v := range verts {
+		if !used[i] {
+			p.DrawDot(v[0], v[1], v[2], radius)
+			if colors[i] != nil {
+				p.lines[len(p.lines)-1].color = colors[i]
+			}
+		}
+	}
+	return nil
+}
+
+// blendColors returns the average of two colors. Missing colors are black.
+func blendColors(a, b color.Color) color.Color {
+	if a == nil {
+		a = color.Black
+	}
+	if b == nil {
+		b = color.Black
+	}
+	if a == b {
+		return a
+	}
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return color.RGBA64{
+		uint16((ar + br) / 2), uint16((ag + bg) / 2),
+		uint16((ab + bb) / 2), uint16((aa + ba) / 2),
+	}
+}
+
+type plyProperty struct {
+	name      string
+	typ       string // scalar type, or the item type of a list
+	countType string // count type of a list, empty for scalars
+}
+
+type plyElement struct {
+	name  string
+	count int
+	props []plyProperty
+}
+
+// index returns the position of the named property, or -1.
+func (e *plyElement) index(name string) int {
+	for i, prop := range e.props {
+		if prop.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// color returns the color from the red, green, blue and alpha properties,
+// or nil when the element has no color. Integer properties range from 0 to
+// 255 and floating point properties from 0 to 1.
+func (e *plyElement) color(values []float64) color.Color {
+	c := [4]uint8{0, 0, 0, 0xff}
+	var found bool
+	for j, name := range [4]string{"red", "green", "blue", "alpha"} {
+		k := e.index(name)
+		if k == -1 {
+			k = e.index("diffuse_" + name)
+		}
+		if k == -1 {
+			continue
+		}
+		found = true
+		switch e.props[k].typ {
+		case "float", "float32", "double", "float64":
+			c[j] = unitByte(values[k])
+		default:
+			c[j] = uint8(math.Max(0, math.Min(255, values[k])))
+		}
+	}
+	if !found {
+		return nil
+	}
+	return color.NRGBA{c[0], c[1], c[2], c[3]}
+}
+
+type plyReader struct {
+	r      *bufio.Reader
+	format string
+	order  binary.ByteOrder
+	fields []string // remaining fields of the current ascii line
+}
+
+func (pr *plyReader) readHeader() ([]*plyElement, error) {
+	line, err := pr.r.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return nil, errors.New("ply: invalid header")
+	}
+	var elements []*plyElement
+	for {
+		line, err := pr.r.ReadString('\n')
+		if err != nil {
+			return nil, errors.New("ply: unexpected end of header")
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return nil, errors.New("ply: invalid format")
+			}
+			pr.format = fields[1]
+			switch pr.format {
+			case "ascii":
+			case "binary_little_endian":
+				pr.order = binary.LittleEndian
+			case "binary_big_endian":
+				pr.order = binary.BigEndian
+			default:
+				return nil, fmt.Errorf("ply: unsupported format: %s", pr.format)
+			}
+		case "element":
+			if len(fields) < 3 {
+				return nil, errors.New("ply: invalid element")
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("ply: invalid element count: %s", fields[2])
+			}
+			elements = append(elements, &plyElement{name: fields[1], count: n})
+		case "property":
+			if len(elements) == 0 {
+				return nil, errors.New("ply: property before element")
+			}
+			var prop plyProperty
+			if len(fields) == 5 && fields[1] == "list" {
+				prop = plyProperty{name: fields[4], typ: fields[3], countType: fields[2]}
+			} else if len(fields) == 3 {
+				prop = plyProperty{name: fields[2], typ: fields[1]}
+			} else {
+				return nil, errors.New("ply: invalid property")
+			}
+			if plyTypeSize(prop.typ) == 0 ||
+				(prop.countType != "" && plyTypeSize(prop.countType) == 0) {
+				return nil, fmt.Errorf("ply: unknown property type: %s", line)
+			}
+			e := elements[len(elements)-1]
+			e.props = append(e.props, prop)
+		case "end_header":
+			if pr.format == "" {
+				return nil, errors.New("ply: missing format")
+			}
+			return elements, nil
+		}
+	}
+}
+
+// readElement reads one instance of the element. Scalar properties are
+// stored in values and list properties in lists, both indexed by property.
+func (pr *plyReader) readElement(e *plyElement, values *[]float64, lists *[][]float64) error {
+	if pr.order == nil {
+		line, err := pr.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		pr.fields = strings.Fields(line)
+	}
+	if len(*values) < len(e.props) {
+		*values = make([]float64, len(e.props))
+		*lists = make([][]float64, len(e.props))
+	}
+	for i, prop := range e.props {
+		if prop.countType == "" {
+			v, err := pr.value(prop.typ)
+			if err != nil {
+				return err
+			}
+			(*values)[i] = v
+			continue
+		}
+		n, err := pr.value(prop.countType)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return errors.New("invalid list length")
+		}
+		list := (*lists)[i][:0]
+		for j := 0; j < int(n); j++ {
+			v, err := pr.value(prop.typ)
+			if err != nil {
+				return err
+			}
+			list = append(list, v)
+		}
+		(*lists)[i] = list
+	}
+	return nil
+}
+
+func (pr *plyReader) value(typ string) (float64, error) {
+	if pr.order == nil {
+		if len(pr.fields) == 0 {
+			return 0, errors.New("not enough values")
+		}
+		s := pr.fields[0]
+		pr.fields = pr.fields[1:]
+		return strconv.ParseFloat(s, 64)
+	}
+	var buf [8]byte
+	b := buf[:plyTypeSize(typ)]
+	if _, err := io.ReadFull(pr.r, b); err != nil {
+		if err == io.EOF {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	switch typ {
+	case "char", "int8":
+		return float64(int8(b[0])), nil
+	case "uchar", "uint8":
+		return float64(b[0]), nil
+	case "short", "int16":
+		return float64(int16(pr.order.Uint16(b))), nil
+	case "ushort", "uint16":
+		return float64(pr.order.Uint16(b)), nil
+	case "int", "int32":
+		return float64(int32(pr.order.Uint32(b))), nil
+	case "uint", "uint32":
+		return float64(pr.order.Uint32(b)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(pr.order.Uint32(b))), nil
+	default: // double, float64
+		return math.Float64frombits(pr.order.Uint64(b)), nil
+	}
+}
+
+// plyTypeSize returns the size in bytes of the scalar type, or zero when the
+// type is unknown.
+func plyTypeSize(typ string) int {
+	switch typ {
+	case "char", "int8", "uchar", "uint8":
+		return 1
+	case "short", "int16", "ushort", "uint16":
+		return 2
+	case "int", "int32", "uint", "uint32", "float", "float32":
+		return 4
+	case "double", "float64":
+		return 8
+	}
+	return 0
+}
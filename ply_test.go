@@ -0,0 +1,95 @@
+package pinhole
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+const plyHeader = `element vertex 3
+property float x
+property double y
+property short z
+property uchar red
+property uchar green
+property uchar blue
+element face 1
+property list uchar int vertex_indices
+end_header
+`
+
+// plyBinary returns the triangle of plyASCII in the binary byte order.
+func plyBinary(format string, order binary.ByteOrder) []byte {
+	var b bytes.Buffer
+	b.WriteString("ply\nformat " + format + " 1.0\n" + plyHeader)
+	for _, v := range [][3]float64{{0, 0, 0}, {1, 0, 0}, {0, 1, -2}} {
+		binary.Write(&b, order, float32(v[0]))
+		binary.Write(&b, order, v[1])
+		binary.Write(&b, order, int16(v[2]))
+		b.Write([]byte{0xff, 0x80, 0})
+	}
+	b.WriteByte(3)
+	binary.Write(&b, order, []int32{0, 1, 2})
+	return b.Bytes()
+}
+
+const plyASCII = "ply\nformat ascii 1.0\n" + plyHeader + `0 0 0 255 128 0
+1 0 0 255 128 0
+0 1 -2 255 128 0
+3 0 1 2
+`
+
+func TestPLYFormats(t *testing.T) {
+	expect := New()
+	if err := expect.LoadPLY(strings.NewReader(plyASCII)); err != nil {
+		t.Fatal(err)
+	}
+	if len(expect.lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(expect.lines))
+	}
+	if l := expect.lines[2]; l.x1 != 0 || l.y1 != 1 || l.z1 != -2 {
+		t.Fatalf("expected the last line from 0,1,-2, got %v,%v,%v", l.x1, l.y1, l.z1)
+	}
+	for _, tc := range []struct {
+		format string
+		order  binary.ByteOrder
+	}{
+		{"binary_little_endian", binary.LittleEndian},
+		{"binary_big_endian", binary.BigEndian},
+	} {
+		p := New()
+		if err := p.LoadPLY(bytes.NewReader(plyBinary(tc.format, tc.order))); err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		if len(p.lines) != len(expect.lines) {
+			t.Fatalf("%s: expected %d lines, got %d", tc.format, len(expect.lines), len(p.lines))
+		}
+		for i, l := range p.lines {
+			e := expect.lines[i]
+			if l.x1 != e.x1 || l.y1 != e.y1 || l.z1 != e.z1 ||
+				l.x2 != e.x2 || l.y2 != e.y2 || l.z2 != e.z2 || l.color != e.color {
+				t.Fatalf("%s: line %d: expected %+v, got %+v", tc.format, i, *e, *l)
+			}
+		}
+	}
+}
+
+func TestPLYErrors(t *testing.T) {
+	bin := plyBinary("binary_little_endian", binary.LittleEndian)
+	for _, tc := range []struct {
+		name string
+		ply  string
+		err  string
+	}{
+		{"header", "obj\n", "invalid header"},
+		{"format", "ply\nformat binary_middle_endian 1.0\nend_header\n", "unsupported format"},
+		{"short", string(bin[:len(bin)-5]), "unexpected EOF"},
+		{"index", strings.Replace(plyASCII, "3 0 1 2", "3 0 1 3", 1), "invalid vertex index"},
+	} {
+		err := New().LoadPLY(strings.NewReader(tc.ply))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected %q error, got %v", tc.name, tc.err, err)
+		}
+	}
+}
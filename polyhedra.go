@@ -0,0 +1,172 @@
+package pinhole
+
+import "math"
+
+// Polyhedron is a kind of solid drawn by DrawPolyhedron.
+type Polyhedron int
+
+const (
+	// the regular solids
+	Tetrahedron Polyhedron = iota
+	Cube
+	Octahedron
+	Dodecahedron
+	Icosahedron
+	// the semi-regular solids
+	TruncatedTetrahedron
+	Cuboctahedron
+	TruncatedCube
+	TruncatedOctahedron
+	Rhombicuboctahedron
+	Icosidodecahedron
+	TruncatedIcosahedron
+	// prisms and antiprisms with square or equilateral sides
+	TriangularPrism
+	PentagonalPrism
+	HexagonalPrism
+	SquareAntiprism
+	PentagonalAntiprism
+)
+
+// DrawPolyhedron draws the edges of the solid with its vertices on a sphere
+// of the radius around x, y, z. Every edge is drawn once. Prisms and
+// antiprisms have their axis on the Y axis. The solid is drawn in its own
+// group.
+func (p *Pinhole) DrawPolyhedron(kind Polyhedron, x, y, z, radius float64) {
+	verts := polyhedronVertices(kind)
+	if len(verts) == 0 {
+		return
+	}
+	// the vertices of these solids are all at the same distance from the
+	// center and the edges are the closest pairs of vertices.
+	scale := radius / vlen(verts[0])
+	edge := math.Inf(1)
+	for i := range verts {
+		for j := i + 1; j < len(verts); j++ {
+			edge = math.Min(edge, vlen(vsub(verts[i], verts[j])))
+		}
+	}
+	center := [3]float64{x, y, z}
+	p.Begin()
+	defer p.End()
+	for i := range verts {
+		for j := i + 1; j < len(verts); j++ {
+			if vlen(vsub(verts[i], verts[j])) > edge*(1+1e-9) {
+				continue
+			}
+			a := vadd(center, vscale(verts[i], scale))
+			b := vadd(center, vscale(verts[j], scale))
+			p.DrawLine(a[0], a[1], a[2], b[0], b[1], b[2])
+		}
+	}
+}
+
+func polyhedronVertices(kind Polyhedron) [][3]float64 {
+	phi := (1 + math.Sqrt(5)) / 2
+	switch kind {
+	case Tetrahedron:
+		return [][3]float64{{1, 1, 1}, {1, -1, -1}, {-1, 1, -1}, {-1, -1, 1}}
+	case Cube:
+		return signs([3]float64{1, 1, 1})
+	case Octahedron:
+		return permutations([3]float64{1, 0, 0}, false)
+	case Dodecahedron:
+		return append(signs([3]float64{1, 1, 1}),
+			permutations([3]float64{0, 1 / phi, phi}, true)...)
+	case Icosahedron:
+		return permutations([3]float64{0, 1, phi}, true)
+	case TruncatedTetrahedron:
+		// the permutations of (3,1,1) with an even number of minus signs
+		var verts [][3]float64
+		for _, v := range permutations([3]float64{3, 1, 1}, false) {
+			if v[0]*v[1]*v[2] > 0 {
+				verts = append(verts, v)
+			}
+		}
+		return verts
+	case Cuboctahedron:
+		return permutations([3]float64{1, 1, 0}, false)
+	case TruncatedCube:
+		return permutations([3]float64{math.Sqrt2 - 1, 1, 1}, false)
+	case TruncatedOctahedron:
+		return permutations([3]float64{0, 1, 2}, false)
+	case Rhombicuboctahedron:
+		return permutations([3]float64{1, 1, 1 + math.Sqrt2}, false)
+	case Icosidodecahedron:
+		return append(permutations([3]float64{0, 0, phi}, true),
+			permutations([3]float64{0.5, phi / 2, phi * phi / 2}, true)...)
+	case TruncatedIcosahedron:
+		verts := permutations([3]float64{0, 1, 3 * phi}, true)
+		verts = append(verts, permutations([3]float64{1, 2 + phi, 2 * phi}, true)...)
+		return append(verts, permutations([3]float64{phi, 2, phi * phi * phi}, true)...)
+	case TriangularPrism:
+		return prismVertices(3, false)
+	case PentagonalPrism:
+		return prismVertices(5, false)
+	case HexagonalPrism:
+		return prismVertices(6, false)
+	case SquareAntiprism:
+		return prismVertices(4, true)
+	case PentagonalAntiprism:
+		return prismVertices(5, true)
+	}
+	return nil
+}
+
+// signs returns the point with every combination of signs of its non-zero
+// coordinates.
+func signs(v [3]float64) [][3]float64 {
+	verts := [][3]float64{v}
+	for i := 0; i < 3; i++ {
+		if v[i] == 0 {
+			continue
+		}
+		for _, u := range verts {
+			u[i] = -u[i]
+			verts = append(verts, u)
+		}
+	}
+	return verts
+}
+
+// permutations returns the even permutations of the coordinates of the
+// point when cyclic is set, or all of the permutations otherwise, with every
+// combination of signs. Repeated points are returned once.
+func permutations(v [3]float64, cyclic bool) [][3]float64 {
+	orders := [][3]int{{0, 1, 2}, {1, 2, 0}, {2, 0, 1}}
+	if !cyclic {
+		orders = append(orders, [3]int{0, 2, 1}, [3]int{2, 1, 0}, [3]int{1, 0, 2})
+	}
+	seen := make(map[[3]float64]bool)
+	var verts [][3]float64
+	for _, o := range orders {
+		for _, u := range signs([3]float64{v[o[0]], v[o[1]], v[o[2]]}) {
+			if !seen[u] {
+				seen[u] = true
+				verts = append(verts, u)
+			}
+		}
+	}
+	return verts
+}
+
+// prismVertices returns the vertices of a prism, or an antiprism, with
+// n-gons on the top and the bottom.
+func prismVertices(n int, anti bool) [][3]float64 {
+	side := 2 * math.Sin(math.Pi/float64(n))
+	height := side
+	twist := 0.0
+	if anti {
+		twist = math.Pi / float64(n)
+		gap := 2 * math.Sin(twist/2)
+		height = math.Sqrt(side*side - gap*gap)
+	}
+	var verts [][3]float64
+	for i := 0; i < n; i++ {
+		a := math.Pi * 2 / float64(n) * float64(i)
+		verts = append(verts,
+			[3]float64{math.Cos(a), -height / 2, math.Sin(a)},
+			[3]float64{math.Cos(a + twist), height / 2, math.Sin(a + twist)})
+	}
+	return verts
+}
@@ -0,0 +1,55 @@
+package pinhole
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPolyhedron(t *testing.T) {
+	for _, tc := range []struct {
+		kind         Polyhedron
+		verts, edges int
+	}{
+		{Tetrahedron, 4, 6},
+		{Cube, 8, 12},
+		{Octahedron, 6, 12},
+		{Dodecahedron, 20, 30},
+		{Icosahedron, 12, 30},
+		{TruncatedTetrahedron, 12, 18},
+		{Cuboctahedron, 12, 24},
+		{TruncatedCube, 24, 36},
+		{TruncatedOctahedron, 24, 36},
+		{Rhombicuboctahedron, 24, 48},
+		{Icosidodecahedron, 30, 60},
+		{TruncatedIcosahedron, 60, 90},
+		{TriangularPrism, 6, 9},
+		{PentagonalPrism, 10, 15},
+		{HexagonalPrism, 12, 18},
+		{SquareAntiprism, 8, 16},
+		{PentagonalAntiprism, 10, 20},
+	} {
+		p := New()
+		p.DrawPolyhedron(tc.kind, 1, 2, 3, 0.5)
+		if len(p.lines) != tc.edges {
+			t.Fatalf("kind %d: expected %d edges, got %d", tc.kind, tc.edges, len(p.lines))
+		}
+		// every vertex is on the sphere and has the same number of edges
+		degree := make(map[[3]float64]int)
+		for _, l := range p.lines {
+			for _, v := range [][3]float64{{l.x1, l.y1, l.z1}, {l.x2, l.y2, l.z2}} {
+				if math.Abs(vlen(vsub(v, [3]float64{1, 2, 3}))-0.5) > 1e-9 {
+					t.Fatalf("kind %d: %v is not on the sphere", tc.kind, v)
+				}
+				degree[v]++
+			}
+		}
+		if len(degree) != tc.verts {
+			t.Fatalf("kind %d: expected %d vertices, got %d", tc.kind, tc.verts, len(degree))
+		}
+		for v, n := range degree {
+			if n != 2*tc.edges/tc.verts {
+				t.Fatalf("kind %d: vertex %v has %d edges", tc.kind, v, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package pinhole
+
+import "math"
+
+type ShapeOptions struct {
+	// Rings is the number of rings around the axis of the shape. For a
+	// sphere these are the latitude rings, not counting the poles.
+	Rings int
+	// Spokes is the number of lines that connect the rings, such as the
+	// meridians of a sphere.
+	Spokes int
+	// Geodesic draws a sphere as an icosphere instead of rings and spokes.
+	Geodesic bool
+	// Subdivisions is the number of times the faces of the icosphere are
+	// divided in four.
+	Subdivisions int
+	// Axis is the direction of the axis of the shape. The zero value is the
+	// Y axis.
+	Axis [3]float64
+}
+
+var DefaultShapeOptions = &ShapeOptions{
+	Rings:        7,
+	Spokes:       12,
+	Subdivisions: 2,
+}
+
+// ringPoints returns n points on the circle around center, starting at
+// center+u*radius and going towards center+v*radius. The u and v vectors
+// must be perpendicular unit vectors.
+func ringPoints(center, u, v [3]float64, radius float64, n int) [][3]float64 {
+	points := make([][3]float64, n)
+	for i := range points {
+		a := math.Pi * 2 / float64(n) * float64(i)
+		points[i] = vadd(center, vadd(vscale(u, math.Cos(a)*radius), vscale(v, math.Sin(a)*radius)))
+	}
+	return points
+}
+
+// basis returns the unit axis of the shape and two unit vectors that are
+// perpendicular to it and to each other.
+func (opts *ShapeOptions) basis() (axis, u, v [3]float64) {
+	axis = vnorm(opts.Axis)
+	if axis == ([3]float64{}) {
+		axis = [3]float64{0, 1, 0}
+	}
+	ref := [3]float64{0, 0, 1}
+	if math.Abs(axis[2]) > 0.9 {
+		ref = [3]float64{1, 0, 0}
+	}
+	u = vnorm(vcross(axis, ref))
+	v = vcross(u, axis)
+	return axis, u, v
+}
+
+// along returns the point at distance d from center along the axis.
+func along(center, axis [3]float64, d float64) [3]float64 {
+	return vadd(center, vscale(axis, d))
+}
+
+// ringSteps returns the number of segments for a ring that must have a
+// vertex at each of n evenly spaced spokes.
+func ringSteps(n int) int {
+	if n <= 0 {
+		return circleSteps
+	}
+	return n * int(math.Ceil(float64(circleSteps)/float64(n)))
+}
+
+// DrawSphere draws a wireframe sphere with its poles on the axis. By default
+// the sphere is drawn as latitude rings and meridians, which are drawn in
+// pairs as full circles so an odd number of Spokes is rounded up. With
+// Geodesic it is drawn as the edges of an icosphere. The sphere is drawn in
+// its own group.
+func (p *Pinhole) DrawSphere(x, y, z, radius float64, opts *ShapeOptions) {
+	if opts == nil {
+		opts = DefaultShapeOptions
+	}
+	center := [3]float64{x, y, z}
+	p.Begin()
+	defer p.End()
+	if opts.Geodesic {
+		p.drawIcosphere(center, radius, opts.Subdivisions)
+		return
+	}
+	axis, u, v := opts.basis()
+	rings, meridians := opts.Rings, (opts.Spokes+1)/2*2
+	// the meridians start at the south pole, where the latitudes start, so
+	// that they share the vertices of the rings and the other way around
+	ringN := ringSteps(meridians)
+	for i := 1; i <= rings; i++ {
+		lat := math.Pi*float64(i)/float64(rings+1) - math.Pi/2
+		p.drawRing(ringPoints(along(center, axis, math.Sin(lat)*radius), u, v,
+			math.Cos(lat)*radius, ringN))
+	}
+	meridianN := ringSteps(2 * (rings + 1))
+	for i := 0; i < meridians/2; i++ {
+		a := math.Pi * 2 / float64(meridians) * float64(i)
+		dir := vadd(vscale(u, math.Cos(a)), vscale(v, math.Sin(a)))
+		p.drawRing(ringPoints(center, vscale(axis, -1), dir, radius, meridianN))
+	}
+}
+
+// drawSpokes draws straight lines between the matching points of rings that
+// have the same number of points, one line for every step points.
+func (p *Pinhole) drawSpokes(rings [][][3]float64, step int) {
+	for i := 1; i < len(rings); i++ {
+		for j := 0; j < len(rings[i]); j += step {
+			a, b := rings[i-1][j], rings[i][j]
+			p.DrawLine(a[0], a[1], a[2], b[0], b[1], b[2])
+		}
+	}
+}
+
+// DrawCylinder draws a wireframe cylinder centered at x, y, z that extends
+// height/2 in both directions along the axis. At least two rings are drawn,
+// one at each end. The cylinder is drawn in its own group.
+func (p *Pinhole) DrawCylinder(x, y, z, radius, height float64, opts *ShapeOptions) {
+	if opts == nil {
+		opts = DefaultShapeOptions
+	}
+	p.Begin()
+	defer p.End()
+	axis, u, v := opts.basis()
+	center := [3]float64{x, y, z}
+	n := ringSteps(opts.Spokes)
+	nrings := opts.Rings
+	if nrings < 2 {
+		nrings = 2
+	}
+	rings := make([][][3]float64, nrings)
+	for i := range rings {
+		d := height*float64(i)/float64(len(rings)-1) - height/2
+		rings[i] = ringPoints(along(center, axis, d), u, v, radius, n)
+		p.drawRing(rings[i])
+	}
+	if opts.Spokes > 0 {
+		p.drawSpokes(rings, n/opts.Spokes)
+	}
+}
+
+// DrawCone draws a wireframe cone centered at x, y, z with its base at
+// height/2 below the center and its apex at height/2 above it, along the
+// axis. The rings are spaced evenly from the base to the apex. The cone is
+// drawn in its own group.
+func (p *Pinhole) DrawCone(x, y, z, radius, height float64, opts *ShapeOptions) {
+	if opts == nil {
+		opts = DefaultShapeOptions
+	}
+	p.Begin()
+	defer p.End()
+	axis, u, v := opts.basis()
+	center := [3]float64{x, y, z}
+	n := ringSteps(opts.Spokes)
+	nrings := opts.Rings
+	if nrings < 1 {
+		nrings = 1
+	}
+	// the last ring is the apex
+	rings := make([][][3]float64, nrings+1)
+	for i := range rings {
+		t := float64(i) / float64(len(rings)-1)
+		c := along(center, axis, height*t-height/2)
+		if i == len(rings)-1 {
+			rings[i] = make([][3]float64, n)
+			for j := range rings[i] {
+				rings[i][j] = c
+			}
+			break
+		}
+		rings[i] = ringPoints(c, u, v, radius*(1-t), n)
+		p.drawRing(rings[i])
+	}
+	if opts.Spokes > 0 {
+		p.drawSpokes(rings, n/opts.Spokes)
+	}
+}
+
+// DrawTorus draws a wireframe torus around the axis. The major radius is the
+// distance from the center to the middle of the tube and the minor radius is
+// the radius of the tube. The rings go around the axis and the spokes are
+// the circles around the tube. The torus is drawn in its own group.
+func (p *Pinhole) DrawTorus(x, y, z, major, minor float64, opts *ShapeOptions) {
+	if opts == nil {
+		opts = DefaultShapeOptions
+	}
+	p.Begin()
+	defer p.End()
+	axis, u, v := opts.basis()
+	center := [3]float64{x, y, z}
+	ringN, tubeN := ringSteps(opts.Spokes), ringSteps(opts.Rings)
+	for i := 0; i < opts.Rings; i++ {
+		a := math.Pi * 2 / float64(opts.Rings) * float64(i)
+		p.drawRing(ringPoints(along(center, axis, math.Sin(a)*minor), u, v,
+			major+math.Cos(a)*minor, ringN))
+	}
+	for i := 0; i < opts.Spokes; i++ {
+		a := math.Pi * 2 / float64(opts.Spokes) * float64(i)
+		dir := vadd(vscale(u, math.Cos(a)), vscale(v, math.Sin(a)))
+		p.drawRing(ringPoints(vadd(center, vscale(dir, major)), dir, axis, minor, tubeN))
+	}
+}
+
+// DrawCapsule draws a wireframe capsule, a cylinder with a hemisphere at each
+// end. The height is the distance between the centers of the hemispheres,
+// which are on the axis at height/2 from x, y, z. Each hemisphere gets half
+// of the rings and the spokes are drawn in pairs as loops over both ends, so
+// an odd number of Spokes is rounded up. The capsule is drawn in its own
+// group.
+func (p *Pinhole) DrawCapsule(x, y, z, radius, height float64, opts *ShapeOptions) {
+	if opts == nil {
+		opts = DefaultShapeOptions
+	}
+	p.Begin()
+	defer p.End()
+	axis, u, v := opts.basis()
+	center := [3]float64{x, y, z}
+	// rings from the equator to the pole, not counting the pole
+	h := opts.Rings/2 + 1
+	spokes := (opts.Spokes + 1) / 2 * 2
+	ringN := ringSteps(spokes)
+	for i := 0; i < h; i++ {
+		lat := math.Pi / 2 * float64(i) / float64(h)
+		d, r := height/2+math.Sin(lat)*radius, math.Cos(lat)*radius
+		p.drawRing(ringPoints(along(center, axis, d), u, v, r, ringN))
+		p.drawRing(ringPoints(along(center, axis, -d), u, v, r, ringN))
+	}
+	// each loop goes over the top from one side to the other and back under
+	// the bottom, sharing the vertices of the rings
+	q := ringSteps(h)
+	for i := 0; i < spokes/2; i++ {
+		a := math.Pi * 2 / float64(spokes) * float64(i)
+		dir := vadd(vscale(u, math.Cos(a)), vscale(v, math.Sin(a)))
+		var loop [][3]float64
+		for _, end := range []float64{1, -1} {
+			c := along(center, axis, end*height/2)
+			for k := 0; k <= 2*q; k++ {
+				b := math.Pi * float64(k) / float64(2*q)
+				pt := vadd(vscale(dir, math.Cos(b)*end*radius), vscale(axis, math.Sin(b)*end*radius))
+				loop = append(loop, vadd(c, pt))
+			}
+		}
+		p.drawRing(loop)
+	}
+}
+
+// drawIcosphere draws each edge of a subdivided icosahedron once.
+func (p *Pinhole) drawIcosphere(center [3]float64, radius float64, subdivisions int) {
+	t := (1 + math.Sqrt(5)) / 2
+	verts := [][3]float64{
+		{-1, t, 0}, {1, t, 0}, {-1, -t, 0}, {1, -t, 0},
+		{0, -1, t}, {0, 1, t}, {0, -1, -t}, {0, 1, -t},
+		{t, 0, -1}, {t, 0, 1}, {-t, 0, -1}, {-t, 0, 1},
+	}
+	for i := range verts {
+		verts[i] = vnorm(verts[i])
+	}
+	faces := [][3]int{
+		{0, 11, 5}, {0, 5, 1}, {0, 1, 7}, {0, 7, 10}, {0, 10, 11},
+		{1, 5, 9}, {5, 11, 4}, {11, 10, 2}, {10, 7, 6}, {7, 1, 8},
+		{3, 9, 4}, {3, 4, 2}, {3, 2, 6}, {3, 6, 8}, {3, 8, 9},
+		{4, 9, 5}, {2, 4, 11}, {6, 2, 10}, {8, 6, 7}, {9, 8, 1},
+	}
+	for level := 0; level < subdivisions; level++ {
+		mids := make(map[[2]int]int)
+		mid := func(a, b int) int {
+			if a > b {
+				a, b = b, a
+			}
+			if i, ok := mids[[2]int{a, b}]; ok {
+				return i
+			}
+			verts = append(verts, vnorm(vadd(verts[a], verts[b])))
+			mids[[2]int{a, b}] = len(verts) - 1
+			return len(verts) - 1
+		}
+		next := make([][3]int, 0, len(faces)*4)
+		for _, f := range faces {
+			a, b, c := mid(f[0], f[1]), mid(f[1], f[2]), mid(f[2], f[0])
+			next = append(next,
+				[3]int{f[0], a, c}, [3]int{f[1], b, a},
+				[3]int{f[2], c, b}, [3]int{a, b, c})
+		}
+		faces = next
+	}
+	drawn := make(map[[2]int]bool)
+	for _, f := range faces {
+		for i := 0; i < 3; i++ {
+			a, b := f[i], f[(i+1)%3]
+			if a > b {
+				a, b = b, a
+			}
+			if drawn[[2]int{a, b}] {
+				continue
+			}
+			drawn[[2]int{a, b}] = true
+			p1 := vadd(center, vscale(verts[a], radius))
+			p2 := vadd(center, vscale(verts[b], radius))
+			p.DrawLine(p1[0], p1[1], p1[2], p2[0], p2[1], p2[2])
+		}
+	}
+}
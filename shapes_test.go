@@ -0,0 +1,66 @@
+package pinhole
+
+import (
+	"math"
+	"testing"
+)
+
+func TestZeroRadius(t *testing.T) {
+	for name, draw := range map[string]func(p *Pinhole){
+		"circle": func(p *Pinhole) { p.DrawCircle(0, 0, 0, 0) },
+		"sphere": func(p *Pinhole) { p.DrawSphere(0, 0, 0, 0, nil) },
+		"geodesic": func(p *Pinhole) {
+			p.DrawSphere(0, 0, 0, 0, &ShapeOptions{Geodesic: true, Subdivisions: 2})
+		},
+		"cylinder": func(p *Pinhole) { p.DrawCylinder(0, 0, 0, 0, 0.5, nil) },
+		"cone":     func(p *Pinhole) { p.DrawCone(0, 0, 0, 0, 0.5, nil) },
+		"torus":    func(p *Pinhole) { p.DrawTorus(0, 0, 0, 0, 0, nil) },
+		"capsule":  func(p *Pinhole) { p.DrawCapsule(0, 0, 0, 0, 0.5, nil) },
+		"ellipse":  func(p *Pinhole) { p.DrawEllipse(0, 0, 0, 0, 0, nil) },
+		"arc":      func(p *Pinhole) { p.DrawArc(0, 0, 0, 0, 0, math.Pi, nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := New()
+			draw(p)
+			p.Image(100, 100, nil)
+		})
+	}
+}
+
+func TestTinyRing(t *testing.T) {
+	// segments of a ring that are shorter than a pixel on the screen
+	p := New()
+	p.DrawSphere(0, 0, 0, 1e-9, nil)
+	p.DrawCircle(0.3, 0, 0, 1e-12)
+	p.DrawArc(0, 0.3, 0, 1e-12, 0, math.Pi, nil)
+	p.Image(100, 100, nil)
+}
+
+func TestSphereSharedVertices(t *testing.T) {
+	for _, rings := range []int{4, 7} {
+		p := New()
+		p.DrawSphere(0, 0, 0, 1, &ShapeOptions{Axis: [3]float64{0, 0, 1}, Rings: rings, Spokes: 12})
+		// the rings are the circles of constant z
+		var ringVerts, meridianVerts [][3]float64
+		for _, l := range p.lines {
+			if l.cfirst.z1 == l.cfirst.z2 && l.z1 == l.z2 {
+				ringVerts = append(ringVerts, [3]float64{l.x1, l.y1, l.z1})
+			} else {
+				meridianVerts = append(meridianVerts, [3]float64{l.x1, l.y1, l.z1})
+			}
+		}
+		var shared int
+		for _, a := range ringVerts {
+			for _, b := range meridianVerts {
+				if math.Abs(a[0]-b[0]) < 1e-9 && math.Abs(a[1]-b[1]) < 1e-9 && math.Abs(a[2]-b[2]) < 1e-9 {
+					shared++
+					break
+				}
+			}
+		}
+		// every ring crosses each of the 12 meridians once
+		if shared != rings*12 {
+			t.Fatalf("rings %d: expected %d shared vertices, got %d", rings, rings*12, shared)
+		}
+	}
+}
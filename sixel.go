@@ -0,0 +1,243 @@
+package pinhole
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sort"
+	"strconv"
+)
+
+type SixelOptions struct {
+	// Colors is the maximum number of palette entries, up to 256.
+	Colors int
+}
+
+var DefaultSixelOptions = &SixelOptions{
+	Colors: 256,
+}
+
+// EncodeSixel writes img to w as a Sixel escape sequence. Images with more
+// colors than allowed by opts are reduced using median cut. Pixels that are
+// mostly transparent are left unpainted.
+func EncodeSixel(w io.Writer, img image.Image, opts *SixelOptions) error {
+	if opts == nil {
+		opts = DefaultSixelOptions
+	}
+	ncolors := opts.Colors
+	if ncolors <= 0 || ncolors > 256 {
+		ncolors = 256
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// gather the opaque pixels and build the palette
+	pixels := make([]uint32, width*height)
+	hist := make(map[uint32]int)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			if c.A < 0x80 {
+				continue
+			}
+			rgb := 1<<24 | uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
+			pixels[y*width+x] = rgb
+			hist[rgb]++
+		}
+	}
+	palette := medianCut(hist, ncolors)
+	index := make(map[uint32]int, len(hist))
+	for rgb := range hist {
+		index[rgb] = nearestColor(palette, rgb)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("\x1bP0;1;0q\"1;1;" + strconv.Itoa(width) + ";" + strconv.Itoa(height))
+	for i, rgb := range palette {
+		bw.WriteString("#" + strconv.Itoa(i) + ";2;" +
+			strconv.Itoa(int(rgb>>16&0xff)*100/0xff) + ";" +
+			strconv.Itoa(int(rgb>>8&0xff)*100/0xff) + ";" +
+			strconv.Itoa(int(rgb&0xff)*100/0xff))
+	}
+	row := make([]byte, width)
+	used := make([]bool, len(palette))
+	for band := 0; band < height; band += 6 {
+		for i := range used {
+			used[i] = false
+		}
+		for y := band; y < band+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				if rgb := pixels[y*width+x]; rgb != 0 {
+					used[index[rgb]] = true
+				}
+			}
+		}
+		first := true
+		for ci := range palette {
+			if !used[ci] {
+				continue
+			}
+			for x := 0; x < width; x++ {
+				var bits byte
+				for dy := 0; dy < 6 && band+dy < height; dy++ {
+					rgb := pixels[(band+dy)*width+x]
+					if rgb != 0 && index[rgb] == ci {
+						bits |= 1 << uint(dy)
+					}
+				}
+				row[x] = '?' + bits
+			}
+			if !first {
+				bw.WriteByte('$')
+			}
+			first = false
+			bw.WriteString("#" + strconv.Itoa(ci))
+			writeSixelRow(bw, row)
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
+
+// writeSixelRow writes the sixel characters in row using run length encoding
+// and drops trailing empty sixels.
+func writeSixelRow(bw *bufio.Writer, row []byte) {
+	for len(row) > 0 && row[len(row)-1] == '?' {
+		row = row[:len(row)-1]
+	}
+	for i := 0; i < len(row); {
+		j := i + 1
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			bw.WriteString("!" + strconv.Itoa(n))
+			bw.WriteByte(row[i])
+		} else {
+			for ; n > 0; n-- {
+				bw.WriteByte(row[i])
+			}
+		}
+		i = j
+	}
+}
+
+// medianCut reduces the colors in hist to at most n colors. The colors in
+// hist are packed as 0xRRGGBB with bit 24 set.
+func medianCut(hist map[uint32]int, n int) []uint32 {
+	colors := make([]uint32, 0, len(hist))
+	for rgb := range hist {
+		colors = append(colors, rgb)
+	}
+	sort.Slice(colors, func(i, j int) bool { return colors[i] < colors[j] })
+	if len(colors) <= n {
+		return colors
+	}
+	boxes := [][]uint32{colors}
+	for len(boxes) < n {
+		// split the box with the widest channel range
+		bi, bch, brange := -1, 0, -1
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for ch := uint(0); ch < 3; ch++ {
+				min, max := 0xff, 0
+				for _, rgb := range box {
+					v := int(rgb >> (ch * 8) & 0xff)
+					if v < min {
+						min = v
+					}
+					if v > max {
+						max = v
+					}
+				}
+				if max-min > brange {
+					bi, bch, brange = i, int(ch), max-min
+				}
+			}
+		}
+		if bi < 0 {
+			break
+		}
+		box := boxes[bi]
+		shift := uint(bch * 8)
+		sort.Slice(box, func(i, j int) bool {
+			return box[i]>>shift&0xff < box[j]>>shift&0xff
+		})
+		var total, half int
+		for _, rgb := range box {
+			total += hist[rgb]
+		}
+		mid := 1
+		for i, rgb := range box[:len(box)-1] {
+			half += hist[rgb]
+			if half*2 >= total {
+				mid = i + 1
+				break
+			}
+		}
+		boxes[bi] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+	palette := make([]uint32, len(boxes))
+	for i, box := range boxes {
+		var r, g, b, total int
+		for _, rgb := range box {
+			c := hist[rgb]
+			r += int(rgb>>16&0xff) * c
+			g += int(rgb>>8&0xff) * c
+			b += int(rgb&0xff) * c
+			total += c
+		}
+		palette[i] = 1<<24 | uint32(r/total)<<16 | uint32(g/total)<<8 | uint32(b/total)
+	}
+	return palette
+}
+
+func nearestColor(palette []uint32, rgb uint32) int {
+	best, bestDist := 0, -1
+	for i, p := range palette {
+		dr := int(rgb>>16&0xff) - int(p>>16&0xff)
+		dg := int(rgb>>8&0xff) - int(p>>8&0xff)
+		db := int(rgb&0xff) - int(p&0xff)
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// EncodeKitty writes img to w using the kitty terminal graphics protocol.
+// The image is transmitted as a PNG, base64 encoded and split into chunks.
+func EncodeKitty(w io.Writer, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+	const chunkSize = 4096
+	bw := bufio.NewWriter(w)
+	for i := 0; i == 0 || i < len(data); i += chunkSize {
+		end := i + chunkSize
+		more := "1"
+		if end >= len(data) {
+			end = len(data)
+			more = "0"
+		}
+		if i == 0 {
+			bw.WriteString("\x1b_Gf=100,a=T,m=" + more + ";")
+		} else {
+			bw.WriteString("\x1b_Gm=" + more + ";")
+		}
+		bw.WriteString(data[i:end])
+		bw.WriteString("\x1b\\")
+	}
+	return bw.Flush()
+}
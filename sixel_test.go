@@ -0,0 +1,75 @@
+package pinhole
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestSixel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 13))
+	for y := 0; y < 13; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 16), 0x80, 0xff})
+		}
+	}
+	for _, colors := range []int{256, 16, 2} {
+		var buf bytes.Buffer
+		if err := EncodeSixel(&buf, img, &SixelOptions{Colors: colors}); err != nil {
+			t.Fatal(err)
+		}
+		s := buf.String()
+		if !strings.HasPrefix(s, "\x1bP0;1;0q\"1;1;16;13") || !strings.HasSuffix(s, "\x1b\\") {
+			t.Fatalf("%d colors: invalid framing: %q", colors, s)
+		}
+		// 16*13 pixels have 208 colors
+		expect := colors
+		if expect > 16*13 {
+			expect = 16 * 13
+		}
+		if n := strings.Count(s, ";2;"); n != expect {
+			t.Fatalf("%d colors: expected %d palette entries, got %d", colors, expect, n)
+		}
+		// three bands of six rows
+		if n := strings.Count(s, "-"); n != 3 {
+			t.Fatalf("%d colors: expected 3 bands, got %d", colors, n)
+		}
+	}
+}
+
+func TestKitty(t *testing.T) {
+	for _, size := range []int{1, 200} {
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		for i := range img.Pix {
+			img.Pix[i] = uint8(i * 7919 >> 3)
+		}
+		var buf bytes.Buffer
+		if err := EncodeKitty(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		chunks := strings.SplitAfter(buf.String(), "\x1b\\")
+		chunks = chunks[:len(chunks)-1]
+		if size > 1 && len(chunks) < 2 {
+			t.Fatalf("size %d: expected more than one chunk", size)
+		}
+		for i, c := range chunks {
+			prefix := "\x1b_Gm="
+			if i == 0 {
+				prefix = "\x1b_Gf=100,a=T,m="
+			}
+			more := "1"
+			if i == len(chunks)-1 {
+				more = "0"
+			}
+			if !strings.HasPrefix(c, prefix+more+";") {
+				t.Fatalf("size %d: chunk %d: invalid header: %q", size, i, c[:20])
+			}
+			data := c[strings.IndexByte(c, ';')+1 : len(c)-2]
+			if len(data) > 4096 {
+				t.Fatalf("size %d: chunk %d: %d bytes", size, i, len(data))
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package pinhole
+
+import "math"
+
+// drawPolyline draws the polyline through the points. Smooth polylines are
+// drawn with joined segments like circles. Polylines with a sharp corner are
+// drawn as separate lines so that the corners stay sharp.
+func (p *Pinhole) drawPolyline(points [][3]float64, closed bool) {
+	smooth := true
+	for i := range points {
+		if !closed && (i == 0 || i == len(points)-1) {
+			continue
+		}
+		prev := points[(i+len(points)-1)%len(points)]
+		next := points[(i+1)%len(points)]
+		d1, d2 := vnorm(vsub(points[i], prev)), vnorm(vsub(next, points[i]))
+		if d1 == ([3]float64{}) || d2 == ([3]float64{}) {
+			continue // repeated points are skipped by drawChain
+		}
+		if vdot(d1, d2) < math.Cos(math.Pi/4) {
+			smooth = false
+			break
+		}
+	}
+	if smooth {
+		p.drawChain(points, closed)
+		return
+	}
+	n := len(points)
+	if !closed {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		a, b := points[i], points[(i+1)%len(points)]
+		p.DrawLine(a[0], a[1], a[2], b[0], b[1], b[2])
+	}
+}
+
+// Extrude draws the closed profile in the XY plane as a prism that extends
+// depth/2 in both directions along the Z axis, with an edge along the prism
+// for each point of the profile. The prism is drawn in its own group.
+func (p *Pinhole) Extrude(profile [][2]float64, depth float64) {
+	if len(profile) < 2 {
+		return
+	}
+	front := make([][3]float64, len(profile))
+	back := make([][3]float64, len(profile))
+	for i, pt := range profile {
+		front[i] = [3]float64{pt[0], pt[1], -depth / 2}
+		back[i] = [3]float64{pt[0], pt[1], depth / 2}
+	}
+	p.Begin()
+	defer p.End()
+	// a profile of two points is a single line
+	p.drawPolyline(front, len(profile) > 2)
+	p.drawPolyline(back, len(profile) > 2)
+	for i := range front {
+		p.DrawLine(front[i][0], front[i][1], front[i][2], back[i][0], back[i][1], back[i][2])
+	}
+}
+
+// Lathe draws the solid that is made by revolving the profile around the
+// axis through the origin. Each point of the profile is a distance from the
+// axis and a position along it. A ring is drawn for each point and the
+// profile is drawn at steps angles around the axis. The zero axis is the Y
+// axis. The solid is drawn in its own group.
+func (p *Pinhole) Lathe(profile [][2]float64, axis [3]float64, steps int) {
+	if len(profile) == 0 {
+		return
+	}
+	a, u, v := (&ShapeOptions{Axis: axis}).basis()
+	p.Begin()
+	defer p.End()
+	n := ringSteps(steps)
+	for _, pt := range profile {
+		if pt[0] != 0 {
+			p.drawRing(ringPoints(along([3]float64{}, a, pt[1]), u, v, pt[0], n))
+		}
+	}
+	if len(profile) < 2 {
+		return
+	}
+	for i := 0; i < steps; i++ {
+		angle := math.Pi * 2 / float64(steps) * float64(i)
+		dir := vadd(vscale(u, math.Cos(angle)), vscale(v, math.Sin(angle)))
+		points := make([][3]float64, len(profile))
+		for j, pt := range profile {
+			points[j] = vadd(vscale(dir, pt[0]), vscale(a, pt[1]))
+		}
+		p.drawPolyline(points, false)
+	}
+}
+
+// Sweep draws the closed profile moved along the path, such as a tube when
+// the profile is a circle. At each point of the path the profile is drawn
+// facing along the path, and an edge follows the path for each point of the
+// profile. The solid is drawn in its own group.
+func (p *Pinhole) Sweep(profile [][2]float64, path [][3]float64) {
+	if len(profile) == 0 || len(path) < 2 {
+		return
+	}
+	tangent := func(i int) [3]float64 {
+		prev, next := path[i], path[i]
+		if i > 0 {
+			prev = path[i-1]
+		}
+		if i < len(path)-1 {
+			next = path[i+1]
+		}
+		return vnorm(vsub(next, prev))
+	}
+	// carry the normal along the path so that the profile does not twist
+	_, normal, _ := (&ShapeOptions{Axis: tangent(0)}).basis()
+	rings := make([][][3]float64, len(path))
+	for i, center := range path {
+		t := tangent(i)
+		if n := vnorm(vsub(normal, vscale(t, vdot(normal, t)))); n != ([3]float64{}) {
+			normal = n
+		}
+		binormal := vcross(t, normal)
+		rings[i] = make([][3]float64, len(profile))
+		for j, pt := range profile {
+			rings[i][j] = vadd(center, vadd(vscale(normal, pt[0]), vscale(binormal, pt[1])))
+		}
+	}
+	p.Begin()
+	defer p.End()
+	for _, ring := range rings {
+		if len(ring) > 1 {
+			// a profile of two points is a single line
+			p.drawPolyline(ring, len(ring) > 2)
+		}
+	}
+	edge := make([][3]float64, len(path))
+	for j := range profile {
+		for i, ring := range rings {
+			edge[i] = ring[j]
+		}
+		p.drawPolyline(edge, false)
+	}
+}
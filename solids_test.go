@@ -0,0 +1,18 @@
+package pinhole
+
+import "testing"
+
+func TestTwoPointProfile(t *testing.T) {
+	profile := [][2]float64{{-0.1, 0}, {0.1, 0}}
+	p := New()
+	p.Sweep(profile, [][3]float64{{0, 0, 0}, {0, 0.5, 0}})
+	// a line for each end of the path and for each point of the profile
+	if len(p.lines) != 4 {
+		t.Fatalf("sweep: expected 4 lines, got %d", len(p.lines))
+	}
+	p = New()
+	p.Extrude(profile, 0.5)
+	if len(p.lines) != 4 {
+		t.Fatalf("extrude: expected 4 lines, got %d", len(p.lines))
+	}
+}
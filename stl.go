@@ -0,0 +1,171 @@
+package pinhole
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func (p *Pinhole) LoadSTL(r io.Reader) error {
+	return p.LoadSTLWithOptions(r, nil)
+}
+
+// LoadSTLWithOptions reads an ASCII or binary STL file from r and draws the
+// edges of each triangle. The format is detected automatically. Named ASCII
+// solids are mapped to named groups, see BeginGroup.
+func (p *Pinhole) LoadSTLWithOptions(r io.Reader, opts *MeshOptions) error {
+	size, err := readerSize(r)
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	mb := newMeshBuilder(p, opts)
+	defer mb.finish()
+	if isASCIISTL(head, size) {
+		return p.loadASCIISTL(br, mb)
+	}
+	return loadBinarySTL(br, mb)
+}
+
+// readerSize returns the number of bytes left in r, or -1 when it is not
+// known.
+func readerSize(r io.Reader) (int64, error) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), nil
+	case io.Seeker:
+		cur, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1, nil
+		}
+		end, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return -1, nil
+		}
+		if _, err := r.Seek(cur, io.SeekStart); err != nil {
+			return -1, err
+		}
+		return end - cur, nil
+	}
+	return -1, nil
+}
+
+// isASCIISTL returns true if the file that starts with head and has the size
+// is an ASCII file. Binary files may also start with the "solid" keyword.
+// When the size is known the file is binary if the size matches the number
+// of triangles in the binary header. Otherwise the file is ASCII if the next
+// line starts a facet or ends the solid, or if it is a single line of text.
+func isASCIISTL(head []byte, size int64) bool {
+	text := bytes.TrimLeft(head, " \t\r\n")
+	if !bytes.HasPrefix(text, []byte("solid")) {
+		return false
+	}
+	if size >= 0 {
+		return len(head) < 84 ||
+			84+50*int64(binary.LittleEndian.Uint32(head[80:])) != size
+	}
+	i := bytes.IndexByte(text, '\n')
+	if i == -1 {
+		// a single line is an empty solid, unless it has binary data
+		for _, c := range text {
+			if c < ' ' && c != '\t' && c != '\r' {
+				return false
+			}
+		}
+		return len(head) < 512
+	}
+	next := bytes.TrimLeft(text[i+1:], " \t\r\n")
+	return bytes.HasPrefix(next, []byte("facet")) ||
+		bytes.HasPrefix(next, []byte("endsolid"))
+}
+
+func (p *Pinhole) loadASCIISTL(r io.Reader, mb *meshBuilder) error {
+	var inSolid bool
+	defer func() {
+		if inSolid {
+			p.End()
+		}
+	}()
+	var loop [][3]float64
+	var inLoop bool
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	for ln := 1; s.Scan(); ln++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "solid":
+			if len(fields) > 1 {
+				p.BeginGroup(strings.Join(fields[1:], " "))
+				inSolid = true
+			}
+		case "endsolid":
+			if inSolid {
+				p.End()
+				inSolid = false
+			}
+		case "outer":
+			loop = loop[:0]
+			inLoop = true
+		case "vertex":
+			if !inLoop {
+				return fmt.Errorf("line %d: vertex outside of loop", ln)
+			}
+			if len(fields) < 4 {
+				return fmt.Errorf("line %d: not enough vertex coordinates", ln)
+			}
+			var v [3]float64
+			for j := 0; j < 3; j++ {
+				var err error
+				if v[j], err = strconv.ParseFloat(fields[j+1], 64); err != nil {
+					return fmt.Errorf("line %d: %s", ln, err.Error())
+				}
+			}
+			loop = append(loop, v)
+		case "endloop":
+			mb.polyline(loop, true, color.Black)
+			inLoop = false
+		}
+	}
+	return s.Err()
+}
+
+func loadBinarySTL(r io.Reader, mb *meshBuilder) error {
+	var header [84]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return errors.New("stl: file too short")
+		}
+		return err
+	}
+	count := binary.LittleEndian.Uint32(header[80:])
+	var tri [50]byte
+	points := make([][3]float64, 3)
+	for i := uint32(0); i < count; i++ {
+		if _, err := io.ReadFull(r, tri[:]); err != nil {
+			if err == io.ErrUnexpectedEOF || err == io.EOF {
+				return fmt.Errorf("stl: triangle %d: unexpected end of file", i)
+			}
+			return err
+		}
+		// skip the normal, the vertices follow
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				bits := binary.LittleEndian.Uint32(tri[12+j*12+k*4:])
+				points[j][k] = float64(math.Float32frombits(bits))
+			}
+		}
+		mb.polyline(points, true, color.Black)
+	}
+	return nil
+}
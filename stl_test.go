@@ -0,0 +1,67 @@
+package pinhole
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+// onlyReader hides the size of the reader.
+type onlyReader struct{ io.Reader }
+
+const stlTriangle = `solid pièce
+  facet normal 0 0 1
+    outer loop
+      vertex 0 0 0
+      vertex 1 0 0
+      vertex 0 1 0
+    endloop
+  endfacet
+endsolid pièce
+`
+
+func stlBinary(header string) []byte {
+	var b bytes.Buffer
+	var h [80]byte
+	copy(h[:], header)
+	b.Write(h[:])
+	binary.Write(&b, binary.LittleEndian, uint32(1))
+	var tri [50]byte
+	for i, v := range []float32{0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1, 0} {
+		binary.LittleEndian.PutUint32(tri[i*4:], math.Float32bits(v))
+	}
+	b.Write(tri[:])
+	return b.Bytes()
+}
+
+func TestSTLDetect(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		r     io.Reader
+		group string
+	}{
+		{"ascii", strings.NewReader(stlTriangle), "pièce"},
+		{"ascii stream", onlyReader{strings.NewReader(stlTriangle)}, "pièce"},
+		{"binary", bytes.NewReader(stlBinary("binary")), ""},
+		{"binary solid", bytes.NewReader(stlBinary("solid made by a tool")), ""},
+		{"binary solid stream", onlyReader{bytes.NewReader(stlBinary("solid made by a tool"))}, ""},
+	} {
+		p := New()
+		if err := p.LoadSTL(tc.r); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(p.lines) != 3 {
+			t.Fatalf("%s: expected 3 lines, got %d", tc.name, len(p.lines))
+		}
+		var name string
+		if g := p.lines[0].group; g != nil {
+			name = g.name
+		}
+		if name != tc.group {
+			t.Fatalf("%s: expected group %q, got %q", tc.name, tc.group, name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package pinhole
+
+import (
+	"image/color"
+	"math"
+)
+
+type SurfaceOptions struct {
+	// Colormap returns the color for t from 0 to 1, where 0 is the lowest Z
+	// of the surface and 1 the highest. Each segment is colored by its
+	// middle. When nil the lines are drawn in the default color.
+	Colormap func(t float64) color.Color
+}
+
+var DefaultSurfaceOptions = &SurfaceOptions{}
+
+// DrawSurface draws the parametric surface f as a mesh of iso-parameter
+// lines, uSteps+1 lines of constant u and vSteps+1 lines of constant v,
+// sampled at the crossings. The lines are broken where f returns NaN. The
+// surface is drawn in its own group.
+func (p *Pinhole) DrawSurface(f func(u, v float64) (x, y, z float64), uRange, vRange [2]float64, uSteps, vSteps int, opts *SurfaceOptions) {
+	if opts == nil {
+		opts = DefaultSurfaceOptions
+	}
+	if uSteps < 1 || vSteps < 1 {
+		return
+	}
+	grid := make([][][3]float64, uSteps+1)
+	zmin, zmax := math.Inf(1), math.Inf(-1)
+	for i := range grid {
+		u := uRange[0] + (uRange[1]-uRange[0])*float64(i)/float64(uSteps)
+		grid[i] = make([][3]float64, vSteps+1)
+		for j := range grid[i] {
+			v := vRange[0] + (vRange[1]-vRange[0])*float64(j)/float64(vSteps)
+			x, y, z := f(u, v)
+			grid[i][j] = [3]float64{x, y, z}
+			if !math.IsNaN(z) {
+				zmin, zmax = math.Min(zmin, z), math.Max(zmax, z)
+			}
+		}
+	}
+	p.Begin()
+	defer p.End()
+	var run [][3]float64
+	flush := func() {
+		if len(run) < 2 {
+			run = run[:0]
+			return
+		}
+		n := len(p.lines)
+		p.drawPolyline(run, false)
+		run = run[:0]
+		if opts.Colormap == nil {
+			return
+		}
+		for _, l := range p.lines[n:] {
+			t := 0.0
+			if zmax > zmin {
+				t = ((l.z1+l.z2)/2 - zmin) / (zmax - zmin)
+			}
+			l.color = opts.Colormap(t)
+		}
+	}
+	add := func(pt [3]float64) {
+		if math.IsNaN(pt[0]) || math.IsNaN(pt[1]) || math.IsNaN(pt[2]) {
+			flush()
+			return
+		}
+		run = append(run, pt)
+	}
+	for i := 0; i <= uSteps; i++ {
+		for j := 0; j <= vSteps; j++ {
+			add(grid[i][j])
+		}
+		flush()
+	}
+	for j := 0; j <= vSteps; j++ {
+		for i := 0; i <= uSteps; i++ {
+			add(grid[i][j])
+		}
+		flush()
+	}
+}
+
+// DrawHeightField draws the surface z = f(x, y) over the ranges of x and y,
+// like DrawSurface.
+func (p *Pinhole) DrawHeightField(f func(x, y float64) float64, xRange, yRange [2]float64, xSteps, ySteps int, opts *SurfaceOptions) {
+	p.DrawSurface(func(u, v float64) (x, y, z float64) {
+		return u, v, f(u, v)
+	}, xRange, yRange, xSteps, ySteps, opts)
+}
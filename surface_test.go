@@ -0,0 +1,62 @@
+package pinhole
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestSurfaceNaN(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		hole             bool
+		segments, chains int
+	}{
+		{"full", false, 40, 10},
+		// the lines through the hole are split in two
+		{"hole", true, 36, 12},
+	} {
+		p := New()
+		p.DrawHeightField(func(x, y float64) float64 {
+			if tc.hole && x == 0 && y == 0 {
+				return math.NaN()
+			}
+			return 0
+		}, [2]float64{-1, 1}, [2]float64{-1, 1}, 4, 4, nil)
+		chains := make(map[*line]bool)
+		for _, l := range p.lines {
+			chains[l.cfirst] = true
+		}
+		if len(p.lines) != tc.segments || len(chains) != tc.chains {
+			t.Fatalf("%s: expected %d segments in %d chains, got %d in %d",
+				tc.name, tc.segments, tc.chains, len(p.lines), len(chains))
+		}
+	}
+}
+
+func TestSurfaceColormap(t *testing.T) {
+	gray := func(t float64) color.Color {
+		return color.Gray16{uint16(math.Round(t * 0xffff))}
+	}
+	p := New()
+	p.DrawHeightField(func(x, y float64) float64 {
+		if x > 0.9 {
+			return math.NaN() // not part of the range of z
+		}
+		return x * 2
+	}, [2]float64{-1, 1}, [2]float64{0, 1}, 10, 2, &SurfaceOptions{Colormap: gray})
+	// z goes from -2 to 1.6 and each segment is colored by its middle
+	var low, high bool
+	for _, l := range p.lines {
+		expect := gray(((l.z1+l.z2)/2 + 2) / 3.6)
+		if l.color != expect {
+			t.Fatalf("segment at z %v to %v: expected %v, got %v", l.z1, l.z2, expect, l.color)
+		}
+		low = low || l.color == gray(0)
+		high = high || l.color == gray(1)
+	}
+	// the lines of constant x reach the ends of the range
+	if !low || !high {
+		t.Fatal("expected the colors of both ends of the range")
+	}
+}
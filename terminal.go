@@ -0,0 +1,321 @@
+package pinhole
+
+import (
+	"bufio"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// TerminalMode is the character set used for drawing to a terminal.
+type TerminalMode int
+
+const (
+	// Braille draws using Unicode braille patterns, 2x4 subpixels per cell.
+	Braille TerminalMode = iota
+	// HalfBlock draws using the upper and lower half block characters, 1x2
+	// subpixels per cell, which allows two colors per cell.
+	HalfBlock
+)
+
+// TerminalColor is the ANSI color encoding used for drawing to a terminal.
+type TerminalColor int
+
+const (
+	// TrueColor uses 24-bit ANSI color codes.
+	TrueColor TerminalColor = iota
+	// Color256 uses the xterm 256 color palette.
+	Color256
+	// NoColor writes characters only.
+	NoColor
+)
+
+type TerminalOptions struct {
+	Mode  TerminalMode
+	Color TerminalColor
+	Scale float64
+}
+
+var DefaultTerminalOptions = &TerminalOptions{
+	Mode:  Braille,
+	Color: TrueColor,
+	Scale: 1,
+}
+
+type termPixel struct {
+	set   bool
+	color color.RGBA
+}
+
+type termText struct {
+	r     rune
+	color color.RGBA
+}
+
+// WriteTerminal draws the scene into a grid of cols by rows characters and
+// writes it to w with ANSI color codes. Black, the default line color, is
+// written using the terminal's default foreground color so that the scene
+// remains visible on dark backgrounds.
+func (p *Pinhole) WriteTerminal(w io.Writer, cols, rows int, opts *TerminalOptions) error {
+	if opts == nil {
+		opts = DefaultTerminalOptions
+	}
+	sx, sy := 2, 4
+	if opts.Mode == HalfBlock {
+		sx, sy = 1, 2
+	}
+	width, height := cols*sx, rows*sy
+	pixels := make([]termPixel, width*height)
+	texts := make([]termText, cols*rows)
+	plot := func(x, y int, c color.RGBA) {
+		if x >= 0 && y >= 0 && x < width && y < height {
+			pixels[y*width+x] = termPixel{true, c}
+		}
+	}
+
+	fwidth, fheight := float64(width), float64(height)
+	plotLine := func(px1, py1, px2, py2 float64, c color.RGBA) {
+		if !clipLine(&px1, &py1, &px2, &py2, fwidth, fheight) {
+			return
+		}
+		steps := math.Ceil(math.Max(math.Abs(px2-px1), math.Abs(py2-py1)))
+		for i := 0.0; i <= steps; i++ {
+			t := 0.0
+			if steps > 0 {
+				t = i / steps
+			}
+			plot(int(px1+(px2-px1)*t), int(py1+(py2-py1)*t), c)
+		}
+	}
+
+	sort.Sort(byDistance(p.lines))
+	focal := math.Min(fwidth, fheight) / 2
+	for _, line := range p.lines {
+		c := color.RGBAModel.Convert(line.color).(color.RGBA)
+		px1, py1 := projectPoint(line.x1, line.y1, line.z1, fwidth, fheight, focal, opts.Scale)
+		px2, py2 := projectPoint(line.x2, line.y2, line.z2, fwidth, fheight, focal, opts.Scale)
+		if line.str != "" {
+			runes := []rune(line.str)
+			col := int(px1)/sx - len(runes)/2
+			row := int(py1) / sy
+			if row < 0 || row >= rows {
+				continue
+			}
+			for i, r := range runes {
+				if col+i >= 0 && col+i < cols {
+					texts[row*cols+col+i] = termText{r, c}
+				}
+			}
+			continue
+		}
+		if line.head {
+			t := lineWidthAtZ(line.z2, focal)
+			if tx, ty, lx, ly, rx, ry, ok := arrowhead(px1, py1, px2, py2, t, line.scale); ok {
+				plotLine(tx, ty, lx, ly, c)
+				plotLine(tx, ty, rx, ry, c)
+			}
+			continue
+		}
+		if px1 == px2 && py1 == py2 {
+			r := lineWidthAtZ(line.z1, focal) * line.scale / 2
+			if r < 1 {
+				plot(int(px1), int(py1), c)
+				continue
+			}
+			if px1+r < 0 || py1+r < 0 || px1-r >= fwidth || py1-r >= fheight {
+				continue
+			}
+			// only visit the pixels of the dot that are on the grid
+			x0, x1 := int(math.Max(0, px1-r)), int(math.Min(fwidth-1, px1+r))
+			y0, y1 := int(math.Max(0, py1-r)), int(math.Min(fheight-1, py1+r))
+			for y := y0; y <= y1; y++ {
+				for x := x0; x <= x1; x++ {
+					dx, dy := float64(x)+0.5-px1, float64(y)+0.5-py1
+					if dx*dx+dy*dy <= r*r {
+						plot(x, y, c)
+					}
+				}
+			}
+			continue
+		}
+		plotLine(px1, py1, px2, py2, c)
+	}
+
+	bw := bufio.NewWriter(w)
+	var fg, bg string
+	setColor := func(nfg, nbg string) {
+		if opts.Color == NoColor || (nfg == fg && nbg == bg) {
+			return
+		}
+		bw.WriteString("\x1b[" + nfg + ";" + nbg + "m")
+		fg, bg = nfg, nbg
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if t := texts[row*cols+col]; t.r != 0 {
+				setColor(ansiColor(t.color, opts.Color, false), "49")
+				bw.WriteRune(t.r)
+				continue
+			}
+			if opts.Mode == HalfBlock {
+				top := pixels[(row*2)*width+col]
+				bot := pixels[(row*2+1)*width+col]
+				switch {
+				case !top.set && !bot.set:
+					if bg != "" && bg != "49" {
+						setColor("39", "49")
+					}
+					bw.WriteByte(' ')
+				case top.set && bot.set && top.color == bot.color:
+					setColor(ansiColor(top.color, opts.Color, false), "49")
+					bw.WriteRune('█')
+				case !top.set:
+					setColor(ansiColor(bot.color, opts.Color, false), "49")
+					bw.WriteRune('▄')
+				case !bot.set:
+					setColor(ansiColor(top.color, opts.Color, false), "49")
+					bw.WriteRune('▀')
+				case isBlack(bot.color):
+					setColor(ansiColor(bot.color, opts.Color, false),
+						ansiColor(top.color, opts.Color, true))
+					bw.WriteRune('▄')
+				default:
+					setColor(ansiColor(top.color, opts.Color, false),
+						ansiColor(bot.color, opts.Color, true))
+					bw.WriteRune('▀')
+				}
+				continue
+			}
+			var bits rune
+			var c color.RGBA
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 2; x++ {
+					px := pixels[(row*4+y)*width+col*2+x]
+					if px.set {
+						bits |= brailleBits[y][x]
+						c = px.color
+					}
+				}
+			}
+			if bits == 0 {
+				bw.WriteByte(' ')
+				continue
+			}
+			setColor(ansiColor(c, opts.Color, false), "49")
+			bw.WriteRune(0x2800 + bits)
+		}
+		if opts.Color != NoColor {
+			bw.WriteString("\x1b[0m")
+			fg, bg = "", ""
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+var brailleBits = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+func isBlack(c color.RGBA) bool {
+	return c.R == 0 && c.G == 0 && c.B == 0
+}
+
+// ansiColor returns the SGR parameters for c as a foreground or background
+// color.
+func ansiColor(c color.RGBA, mode TerminalColor, background bool) string {
+	if isBlack(c) {
+		if background {
+			return "49"
+		}
+		return "39"
+	}
+	prefix := "38;"
+	if background {
+		prefix = "48;"
+	}
+	if mode == Color256 {
+		return prefix + "5;" + strconv.Itoa(xterm256(c))
+	}
+	return prefix + "2;" + strconv.Itoa(int(c.R)) + ";" +
+		strconv.Itoa(int(c.G)) + ";" + strconv.Itoa(int(c.B))
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// xterm256 returns the closest xterm 256 color palette index for c, using
+// either the 6x6x6 color cube or the grayscale ramp.
+func xterm256(c color.RGBA) int {
+	nearest := func(v uint8) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(int(v)-l) < abs(int(v)-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	r, g, b := nearest(c.R), nearest(c.G), nearest(c.B)
+	cube := 16 + 36*r + 6*g + b
+	cubeDist := sqdist(c, cubeLevels[r], cubeLevels[g], cubeLevels[b])
+
+	avg := (int(c.R) + int(c.G) + int(c.B)) / 3
+	gray := (avg - 8 + 5) / 10
+	if gray < 0 {
+		gray = 0
+	} else if gray > 23 {
+		gray = 23
+	}
+	lvl := 8 + gray*10
+	if sqdist(c, lvl, lvl, lvl) < cubeDist {
+		return 232 + gray
+	}
+	return cube
+}
+
+func sqdist(c color.RGBA, r, g, b int) int {
+	dr, dg, db := int(c.R)-r, int(c.G)-g, int(c.B)-b
+	return dr*dr + dg*dg + db*db
+}
+
+// clipLine clips the line to the rectangle 0,0,w,h using the Liang-Barsky
+// algorithm. Returns false when the line is entirely outside.
+func clipLine(x1, y1, x2, y2 *float64, w, h float64) bool {
+	t0, t1 := 0.0, 1.0
+	dx, dy := *x2-*x1, *y2-*y1
+	p := [4]float64{-dx, dx, -dy, dy}
+	q := [4]float64{*x1, w - *x1, *y1, h - *y1}
+	for i := 0; i < 4; i++ {
+		if p[i] == 0 {
+			if q[i] < 0 {
+				return false
+			}
+			continue
+		}
+		r := q[i] / p[i]
+		if p[i] < 0 {
+			if r > t1 {
+				return false
+			}
+			if r > t0 {
+				t0 = r
+			}
+		} else {
+			if r < t0 {
+				return false
+			}
+			if r < t1 {
+				t1 = r
+			}
+		}
+	}
+	nx1, ny1 := *x1+t0*dx, *y1+t0*dy
+	nx2, ny2 := *x1+t1*dx, *y1+t1*dy
+	*x1, *y1, *x2, *y2 = nx1, ny1, nx2, ny2
+	return true
+}
@@ -0,0 +1,32 @@
+package pinhole
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTerminalDots(t *testing.T) {
+	opts := &TerminalOptions{Mode: Braille, Color: NoColor, Scale: 1}
+	for _, tc := range []struct {
+		name   string
+		x      float64
+		radius float64
+		cell   string
+	}{
+		// covers the grid many times over and only the grid is visited
+		{"huge", 0, 1e4, "⣿"},
+		{"off", 3, 0.01, " "},
+	} {
+		p := New()
+		p.DrawDot(tc.x, 0, 0, tc.radius)
+		var buf bytes.Buffer
+		if err := p.WriteTerminal(&buf, 10, 5, opts); err != nil {
+			t.Fatal(err)
+		}
+		expect := strings.Repeat(strings.Repeat(tc.cell, 10)+"\n", 5)
+		if buf.String() != expect {
+			t.Fatalf("%s: expected\n%q\ngot\n%q", tc.name, expect, buf.String())
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package pinhole
+
+import (
+	"bufio"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteTikZ writes the scene to w as a TikZ picture for LaTeX documents. The
+// picture is width by height points and is drawn like Image, back to front,
+// with line widths in points. Colors are defined with \definecolor and the
+// strings are written as nodes so that they use the document font.
+func (p *Pinhole) WriteTikZ(w io.Writer, width, height float64, opts *ImageOptions) error {
+	if opts == nil {
+		opts = DefaultImageOptions
+	}
+	sort.Sort(byDistance(p.lines))
+	focal := math.Min(width, height) / 2
+	project := func(x, y, z float64) string {
+		px, py := projectPoint(x, y, z, width, height, focal, opts.Scale)
+		return "(" + tikzNum(px) + "," + tikzNum(height-py) + ")"
+	}
+	lineWidth := func(z, scale float64) float64 {
+		return lineWidthAtZ(z, focal) * opts.LineWidth * scale
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("\\begin{tikzpicture}[x=1pt,y=1pt]\n")
+	colors := make(map[color.NRGBA]string)
+	colorName := func(c color.Color) string {
+		nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+		name, ok := colors[nc]
+		if !ok {
+			name = "pinhole" + strconv.Itoa(len(colors))
+			colors[nc] = name
+			bw.WriteString("\\definecolor{" + name + "}{RGB}{" +
+				strconv.Itoa(int(nc.R)) + "," + strconv.Itoa(int(nc.G)) + "," +
+				strconv.Itoa(int(nc.B)) + "}\n")
+		}
+		if nc.A != 0xff {
+			name += ",opacity=" + tikzNum(float64(nc.A)/0xff)
+		}
+		return name
+	}
+	if opts.BGColor != nil {
+		bw.WriteString("\\fill[" + colorName(opts.BGColor) + "] (0,0) rectangle (" +
+			tikzNum(width) + "," + tikzNum(height) + ");\n")
+	}
+	bw.WriteString("\\clip (0,0) rectangle (" + tikzNum(width) + "," + tikzNum(height) + ");\n")
+	circles := make(map[*line]bool)
+	for _, l := range p.lines {
+		switch {
+		case l.str != "":
+			sz := tikzNum(10 * lineWidth(l.z1, l.scale))
+			bw.WriteString("\\node[text=" + colorName(l.color) +
+				",inner sep=0,font=\\fontsize{" + sz + "}{" + sz + "}\\selectfont] at " +
+				project(l.x1, l.y1, l.z1) + " {" + texEscape(l.str) + "};\n")
+		case l.head:
+			x1, y1 := projectPoint(l.x1, l.y1, l.z1, width, height, focal, opts.Scale)
+			x2, y2 := projectPoint(l.x2, l.y2, l.z2, width, height, focal, opts.Scale)
+			tx, ty, lx, ly, rx, ry, ok := arrowhead(x1, height-y1, x2, height-y2, lineWidth(l.z2, 1), l.scale)
+			if ok {
+				bw.WriteString("\\fill[" + colorName(l.color) + "] (" +
+					tikzNum(tx) + "," + tikzNum(ty) + ") -- (" +
+					tikzNum(lx) + "," + tikzNum(ly) + ") -- (" +
+					tikzNum(rx) + "," + tikzNum(ry) + ") -- cycle;\n")
+			}
+		case l.circle:
+			if circles[l.cfirst] {
+				continue
+			}
+			circles[l.cfirst] = true
+			// draw runs of segments that share a color as one path
+			var path []string
+			var total float64
+			var run *line
+			lineCap := "butt"
+			if l.copen {
+				lineCap = "round"
+			}
+			flush := func() {
+				end := ";\n"
+				if len(path) > 2 && path[0] == path[len(path)-1] {
+					path, end = path[:len(path)-1], " -- cycle;\n"
+				}
+				bw.WriteString("\\draw[" + colorName(run.color) + ",line width=" +
+					tikzNum(total) + "pt,line join=round,line cap=" + lineCap + "] " +
+					strings.Join(path, " -- ") + end)
+			}
+			var n int
+			for seg := l.cfirst; seg != nil; seg = seg.cnext {
+				if run != nil && seg.color != run.color {
+					total /= float64(n)
+					flush()
+					path, total, n = path[:0], 0, 0
+				}
+				if len(path) == 0 {
+					run = seg
+					path = append(path, project(seg.x1, seg.y1, seg.z1))
+				}
+				path = append(path, project(seg.x2, seg.y2, seg.z2))
+				total += lineWidth(seg.z1, seg.scale)
+				n++
+			}
+			total /= float64(n)
+			flush()
+		case l.x1 == l.x2 && l.y1 == l.y2 && l.z1 == l.z2:
+			bw.WriteString("\\fill[" + colorName(l.color) + "] " +
+				project(l.x1, l.y1, l.z1) + " circle[radius=" +
+				tikzNum(lineWidth(l.z1, l.scale)/2) + "pt];\n")
+		default:
+			lineCap := "round"
+			if l.nocaps {
+				lineCap = "butt"
+			}
+			t := (lineWidth(l.z1, l.scale) + lineWidth(l.z2, l.scale)) / 2
+			bw.WriteString("\\draw[" + colorName(l.color) + ",line width=" +
+				tikzNum(t) + "pt,line cap=" + lineCap + "] " +
+				project(l.x1, l.y1, l.z1) + " -- " + project(l.x2, l.y2, l.z2) + ";\n")
+		}
+	}
+	bw.WriteString("\\end{tikzpicture}\n")
+	return bw.Flush()
+}
+
+// tikzNum formats the number with up to three decimals.
+func tikzNum(f float64) string {
+	return strconv.FormatFloat(math.Round(f*1000)/1000, 'f', -1, 64)
+}
+
+var texReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`#`, `\#`,
+	`$`, `\$`,
+	`%`, `\%`,
+	`&`, `\&`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+// texEscape escapes the characters that are special to LaTeX.
+func texEscape(s string) string {
+	return texReplacer.Replace(s)
+}
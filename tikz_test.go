@@ -0,0 +1,47 @@
+package pinhole
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestTikZ(t *testing.T) {
+	p := New()
+	p.DrawLine(-0.5, 0, 0, 0.5, 0, 0)
+	p.DrawLine(0, -0.5, 0, 0, 0.5, 0)
+	p.Begin()
+	p.DrawCircle(0, 0, 0, 0.3)
+	p.Colorize(color.RGBA{0xff, 0, 0, 0xff})
+	p.End()
+	p.DrawDot(0.2, 0.2, 0, 0.05)
+	p.DrawString(-0.2, 0.2, 0, "a_b")
+	var buf bytes.Buffer
+	if err := p.WriteTikZ(&buf, 200, 100, nil); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.HasPrefix(s, "\\begin{tikzpicture}") || !strings.HasSuffix(s, "\\end{tikzpicture}\n") {
+		t.Fatalf("invalid picture: %s", s)
+	}
+	// two lines and one path for the circle
+	if n := strings.Count(s, "\\draw["); n != 3 {
+		t.Fatalf("expected 3 draws, got %d:\n%s", n, s)
+	}
+	for _, want := range []string{
+		"{RGB}{0,0,0}",       // lines, dot and string
+		"{RGB}{255,0,0}",     // circle
+		"{RGB}{255,255,255}", // background
+		"-- cycle;",
+		"circle[radius=",
+		"{a\\_b}",
+	} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("missing %q:\n%s", want, s)
+		}
+	}
+	if n := strings.Count(s, "\\definecolor"); n != 3 {
+		t.Fatalf("expected 3 colors, got %d", n)
+	}
+}
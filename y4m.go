@@ -0,0 +1,151 @@
+package pinhole
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// Y4MChroma is the chroma subsampling of a YUV4MPEG2 stream.
+type Y4MChroma int
+
+const (
+	// Chroma420 halves the chroma resolution in both directions, with the
+	// chroma samples centered between the luma samples.
+	Chroma420 Y4MChroma = iota
+	// Chroma422 halves the horizontal chroma resolution.
+	Chroma422
+	// Chroma444 keeps the full chroma resolution.
+	Chroma444
+)
+
+type Y4MOptions struct {
+	// FrameRateNum / FrameRateDen is the number of frames per second, such
+	// as 30000/1001 for NTSC.
+	FrameRateNum int
+	FrameRateDen int
+	Chroma       Y4MChroma
+}
+
+var DefaultY4MOptions = &Y4MOptions{
+	FrameRateNum: 25,
+	FrameRateDen: 1,
+	Chroma:       Chroma420,
+}
+
+// Y4MWriter writes images as the frames of a YUV4MPEG2 video stream, which
+// encoders such as ffmpeg and x264 read from a pipe. The colors are written
+// in full range.
+type Y4MWriter struct {
+	w             io.Writer
+	width, height int
+	opts          *Y4MOptions
+	header        bool
+	buf           []byte
+}
+
+// NewY4MWriter returns a writer of width by height frames to w. The stream
+// header is written with the first frame.
+func NewY4MWriter(w io.Writer, width, height int, opts *Y4MOptions) *Y4MWriter {
+	if opts == nil {
+		opts = DefaultY4MOptions
+	}
+	return &Y4MWriter{w: w, width: width, height: height, opts: opts}
+}
+
+// chromaSize returns the size of the chroma planes.
+func (y *Y4MWriter) chromaSize() (int, int) {
+	switch y.opts.Chroma {
+	case Chroma420:
+		return (y.width + 1) / 2, (y.height + 1) / 2
+	case Chroma422:
+		return (y.width + 1) / 2, y.height
+	}
+	return y.width, y.height
+}
+
+// WriteFrame writes the image as the next frame. The image must have the size
+// of the stream. Transparent pixels are written as if drawn over black.
+func (y *Y4MWriter) WriteFrame(img image.Image) error {
+	bounds := img.Bounds()
+	if bounds.Dx() != y.width || bounds.Dy() != y.height {
+		return errors.New("frame size does not match the stream")
+	}
+	if y.width <= 0 || y.height <= 0 {
+		return errors.New("invalid frame size")
+	}
+	if !y.header {
+		var chroma string
+		switch y.opts.Chroma {
+		case Chroma420:
+			chroma = "420jpeg"
+		case Chroma422:
+			chroma = "422"
+		case Chroma444:
+			chroma = "444"
+		default:
+			return errors.New("invalid chroma subsampling")
+		}
+		num, den := y.opts.FrameRateNum, y.opts.FrameRateDen
+		if num <= 0 {
+			return errors.New("invalid frame rate")
+		}
+		if den <= 0 {
+			den = 1
+		}
+		header := "YUV4MPEG2 W" + strconv.Itoa(y.width) + " H" + strconv.Itoa(y.height) +
+			" F" + strconv.Itoa(num) + ":" + strconv.Itoa(den) +
+			" Ip A1:1 C" + chroma + " XCOLORRANGE=FULL\n"
+		if _, err := io.WriteString(y.w, header); err != nil {
+			return err
+		}
+		y.header = true
+	}
+
+	cw, ch := y.chromaSize()
+	sx, sy := (y.width+cw-1)/cw, (y.height+ch-1)/ch
+	n := len("FRAME\n") + y.width*y.height + cw*ch*2
+	if cap(y.buf) < n {
+		y.buf = make([]byte, n)
+	}
+	buf := y.buf[:n]
+	copy(buf, "FRAME\n")
+	lum := buf[len("FRAME\n"):]
+	cb := lum[y.width*y.height:]
+	cr := cb[cw*ch:]
+
+	// convert each pixel and sum the chroma of the pixels sharing a sample
+	rgba, _ := img.(*image.RGBA)
+	sums := make([][3]int, cw)
+	for cy := 0; cy < ch; cy++ {
+		for i := range sums {
+			sums[i] = [3]int{}
+		}
+		for py := cy * sy; py < (cy+1)*sy && py < y.height; py++ {
+			for px := 0; px < y.width; px++ {
+				var r, g, b uint8
+				if rgba != nil {
+					i := rgba.PixOffset(bounds.Min.X+px, bounds.Min.Y+py)
+					r, g, b = rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2]
+				} else {
+					r16, g16, b16, _ := img.At(bounds.Min.X+px, bounds.Min.Y+py).RGBA()
+					r, g, b = uint8(r16>>8), uint8(g16>>8), uint8(b16>>8)
+				}
+				yy, u, v := color.RGBToYCbCr(r, g, b)
+				lum[py*y.width+px] = yy
+				s := &sums[px/sx]
+				s[0] += int(u)
+				s[1] += int(v)
+				s[2]++
+			}
+		}
+		for cx, s := range sums {
+			cb[cy*cw+cx] = uint8((s[0] + s[2]/2) / s[2])
+			cr[cy*cw+cx] = uint8((s[1] + s[2]/2) / s[2])
+		}
+	}
+	_, err := y.w.Write(buf)
+	return err
+}
@@ -0,0 +1,62 @@
+package pinhole
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestY4M(t *testing.T) {
+	const w, h = 7, 5
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	img.Set(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+	for _, tc := range []struct {
+		chroma Y4MChroma
+		name   string
+		cw, ch int
+	}{
+		{Chroma420, "420jpeg", 4, 3},
+		{Chroma422, "422", 4, 5},
+		{Chroma444, "444", 7, 5},
+	} {
+		var buf bytes.Buffer
+		yw := NewY4MWriter(&buf, w, h, &Y4MOptions{FrameRateNum: 30000, FrameRateDen: 1001, Chroma: tc.chroma})
+		for i := 0; i < 2; i++ {
+			if err := yw.WriteFrame(img); err != nil {
+				t.Fatal(err)
+			}
+		}
+		header := "YUV4MPEG2 W7 H5 F30000:1001 Ip A1:1 C" + tc.name + " XCOLORRANGE=FULL\n"
+		s := buf.String()
+		if !strings.HasPrefix(s, header) {
+			t.Fatalf("%s: invalid header: %q", tc.name, s[:strings.IndexByte(s, '\n')+1])
+		}
+		frames := strings.Split(s[len(header):], "FRAME\n")
+		if len(frames) != 3 || frames[0] != "" {
+			t.Fatalf("%s: expected 2 frames, got %d", tc.name, len(frames)-1)
+		}
+		for _, f := range frames[1:] {
+			if len(f) != w*h+tc.cw*tc.ch*2 {
+				t.Fatalf("%s: expected %d bytes per frame, got %d", tc.name, w*h+tc.cw*tc.ch*2, len(f))
+			}
+			// white is full range luma, the red pixel is darker
+			if f[0] == 0xff || f[w*h-1] != 0xff {
+				t.Fatalf("%s: invalid luma plane", tc.name)
+			}
+			// red raises Cr and lowers Cb of the first sample
+			cb, cr := f[w*h], f[w*h+tc.cw*tc.ch]
+			if cr <= 0x80 || cb >= 0x80 {
+				t.Fatalf("%s: invalid chroma %d %d", tc.name, cb, cr)
+			}
+		}
+	}
+	yw := NewY4MWriter(&bytes.Buffer{}, w, h, nil)
+	if err := yw.WriteFrame(image.NewRGBA(image.Rect(0, 0, w+1, h))); err == nil {
+		t.Fatal("expected a frame size error")
+	}
+}